@@ -0,0 +1,152 @@
+/*
+Package checks - 5 Container Runtime (kernel capabilities)
+A container's capabilities follow from the runtime's default set, the
+capabilities added and dropped when it was created, and privileged mode. The
+check works on the resulting set rather than on the flags alone, so that a
+container dropping ALL and adding back what it needs is not reported.
+*/
+package actuary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capabilities granted by the Docker runtime when none are added or dropped
+var defaultCapabilities = []string{"CHOWN", "DAC_OVERRIDE", "FSETID", "FOWNER", "MKNOD",
+	"NET_RAW", "SETGID", "SETUID", "SETFCAP", "SETPCAP", "NET_BIND_SERVICE", "SYS_CHROOT",
+	"KILL", "AUDIT_WRITE"}
+
+// Every capability known to the kernel, as granted by privileged mode or "ALL",
+// in the order of their numbers
+var allCapabilities = []string{"CHOWN", "DAC_OVERRIDE", "DAC_READ_SEARCH", "FOWNER", "FSETID",
+	"KILL", "SETGID", "SETUID", "SETPCAP", "LINUX_IMMUTABLE", "NET_BIND_SERVICE",
+	"NET_BROADCAST", "NET_ADMIN", "NET_RAW", "IPC_LOCK", "IPC_OWNER", "SYS_MODULE",
+	"SYS_RAWIO", "SYS_CHROOT", "SYS_PTRACE", "SYS_PACCT", "SYS_ADMIN", "SYS_BOOT", "SYS_NICE",
+	"SYS_RESOURCE", "SYS_TIME", "SYS_TTY_CONFIG", "MKNOD", "LEASE", "AUDIT_WRITE",
+	"AUDIT_CONTROL", "SETFCAP", "MAC_OVERRIDE", "MAC_ADMIN", "SYSLOG", "WAKE_ALARM",
+	"BLOCK_SUSPEND", "AUDIT_READ", "PERFMON", "BPF", "CHECKPOINT_RESTORE"}
+
+// capabilityRisks classifies the capabilities that weaken container isolation.
+// "high" ones allow escaping to the host outright, "medium" ones allow attacking
+// the host or other containers over the network or through the kernel.
+var capabilityRisks = map[string]string{
+	"SYS_ADMIN":       "high",
+	"SYS_MODULE":      "high",
+	"SYS_PTRACE":      "high",
+	"SYS_RAWIO":       "high",
+	"DAC_READ_SEARCH": "high",
+	"SYS_BOOT":        "high",
+	"MAC_ADMIN":       "high",
+	"MAC_OVERRIDE":    "high",
+	"BPF":             "high",
+	"NET_ADMIN":       "medium",
+	"NET_RAW":         "medium",
+	"SYS_TIME":        "medium",
+	"SYSLOG":          "medium",
+	"PERFMON":         "medium",
+	"LINUX_IMMUTABLE": "medium",
+}
+
+// CheckKernelCapabilities reports the capabilities containers hold beyond what
+// they are allowed. Containers picked by a capability rule of the profile may
+// hold the capabilities of the rules matching them; other containers may hold
+// the runtime's default set.
+func CheckKernelCapabilities(t Target) (res Result) {
+	var findings []string
+	res.Name = "5.3 Restrict Linux Kernel Capabilities within containers"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		allowed, matched := allowedCapabilities(t.Policy.Capabilities, c)
+		if !matched {
+			allowed = defaultCapabilities
+		}
+		caps := effectiveCapabilities(c.Info)
+		var excess []string
+		// Risky capabilities are listed first so they are not summarized away
+		for _, risk := range []string{"high", "medium", ""} {
+			for _, capability := range caps {
+				if stringInSlice(capability, allowed) || capabilityRisks[capability] != risk {
+					continue
+				}
+				if risk != "" {
+					capability = fmt.Sprintf("%s (%s)", capability, risk)
+				}
+				excess = append(excess, capability)
+			}
+		}
+		if len(excess) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, summarize(excess, 10)))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers with capabilities beyond the allowed set: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+// effectiveCapabilities returns the sorted capabilities a container runs with
+func effectiveCapabilities(info ContainerInfo) (caps []string) {
+	hc := info.HostConfig
+	if hc.Privileged {
+		caps = append(caps, allCapabilities...)
+		sort.Strings(caps)
+		return
+	}
+	added := normalizeCapabilities(hc.CapAdd)
+	dropped := normalizeCapabilities(hc.CapDrop)
+	set := make(map[string]bool)
+	switch {
+	case stringInSlice("ALL", added):
+		for _, capability := range allCapabilities {
+			set[capability] = true
+		}
+	case !stringInSlice("ALL", dropped):
+		for _, capability := range defaultCapabilities {
+			set[capability] = true
+		}
+	}
+	// Explicitly added capabilities win over dropped ones, as in the runtime
+	for _, capability := range dropped {
+		delete(set, capability)
+	}
+	for _, capability := range added {
+		if capability != "ALL" {
+			set[capability] = true
+		}
+	}
+	for capability := range set {
+		caps = append(caps, capability)
+	}
+	sort.Strings(caps)
+	return
+}
+
+// allowedCapabilities returns the union of the capabilities allowed by the
+// rules picking a container, and whether any rule did
+func allowedCapabilities(rules []CapabilityRule, c Container) (allowed []string, matched bool) {
+	for _, rule := range rules {
+		if rule.matches(c) {
+			matched = true
+			allowed = append(allowed, normalizeCapabilities(rule.Allowed)...)
+		}
+	}
+	return
+}
+
+// normalizeCapabilities turns "cap_net_admin" and "NET_ADMIN" alike into "NET_ADMIN"
+func normalizeCapabilities(caps []string) (normalized []string) {
+	for _, capability := range caps {
+		normalized = append(normalized, strings.TrimPrefix(strings.ToUpper(capability), "CAP_"))
+	}
+	return
+}
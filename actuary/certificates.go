@@ -0,0 +1,306 @@
+/*
+Package checks - 3 Docker daemon configuration files (TLS certificates)
+Beyond ownership and permissions, the certificates securing the daemon and registry
+connections must themselves be sound: current, signed with strong algorithms, issued
+for the addresses the daemon listens on and chained to the configured CA.
+*/
+package actuary
+
+import (
+	"bytes"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func CheckCertExpiry(t Target) (res Result) {
+	var badCerts []string
+	res.Name = "Verify that TLS certificates are not expired or about to expire"
+	certs := getCertificates(t)
+	if len(certs) == 0 {
+		res.Skip("No TLS certificates found")
+		return
+	}
+	days := t.Policy.TLS.expiryWarningDays()
+	now := time.Now()
+	deadline := now.AddDate(0, 0, days)
+	for path, chain := range certs {
+		for _, cert := range chain {
+			if now.After(cert.NotAfter) {
+				badCerts = append(badCerts, fmt.Sprintf("%s (%s) expired on %s",
+					path, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+			} else if deadline.After(cert.NotAfter) {
+				badCerts = append(badCerts, fmt.Sprintf("%s (%s) expires on %s",
+					path, cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+			}
+		}
+	}
+	if len(badCerts) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Certificates expired or expiring within %d days: %s",
+			days, badCerts)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckCertKeyStrength(t Target) (res Result) {
+	var weakKeys []string
+	res.Name = "Verify that TLS certificates and keys use strong algorithms and key sizes"
+	certs := getCertificates(t)
+	keyPath := getDaemonOpt(t, "--tlskey")
+	if len(certs) == 0 && keyPath == "" {
+		res.Skip("No TLS certificates found")
+		return
+	}
+	for path, chain := range certs {
+		for _, cert := range chain {
+			if weakness := keyWeakness(cert.PublicKey, t.Policy.TLS); weakness != "" {
+				weakKeys = append(weakKeys, fmt.Sprintf("%s (%s): %s",
+					path, cert.Subject.CommonName, weakness))
+			}
+		}
+	}
+	if keyPath != "" {
+		keyPath = filepath.Join(t.BaseDir, keyPath)
+		key, err := readPublicKey(keyPath)
+		if err == nil {
+			if weakness := keyWeakness(key, t.Policy.TLS); weakness != "" {
+				weakKeys = append(weakKeys, fmt.Sprintf("%s: %s", keyPath, weakness))
+			}
+		}
+	}
+	if len(weakKeys) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Weak keys found: %s", weakKeys)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckCertSignature(t Target) (res Result) {
+	var badCerts []string
+	res.Name = "Verify that TLS certificates are not signed with SHA-1 or MD5"
+	certs := getCertificates(t)
+	if len(certs) == 0 {
+		res.Skip("No TLS certificates found")
+		return
+	}
+	for path, chain := range certs {
+		for _, cert := range chain {
+			// The signature on a self-signed root is never verified
+			if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+				continue
+			}
+			switch cert.SignatureAlgorithm {
+			case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA,
+				x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+				badCerts = append(badCerts, fmt.Sprintf("%s (%s): %s",
+					path, cert.Subject.CommonName, cert.SignatureAlgorithm))
+			}
+		}
+	}
+	if len(badCerts) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Certificates with weak signatures: %s", badCerts)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckServerCertSAN(t Target) (res Result) {
+	var missing []string
+	res.Name = "Verify that the Docker server certificate covers the daemon's listen addresses"
+	chain, err := getServerChain(t)
+	if err != nil {
+		res.Skip(err.Error())
+		return
+	}
+	cert := chain[0]
+	if len(cert.DNSNames) == 0 && len(cert.IPAddresses) == 0 {
+		res.Fail("Server certificate has no subject alternative names")
+		return
+	}
+	for _, host := range getDaemonHosts(t) {
+		u, err := url.Parse(host)
+		if err != nil || u.Scheme != "tcp" {
+			continue
+		}
+		hostname := u.Hostname()
+		if ip := net.ParseIP(hostname); hostname == "" || (ip != nil && ip.IsUnspecified()) {
+			continue
+		}
+		if cert.VerifyHostname(hostname) != nil {
+			missing = append(missing, hostname)
+		}
+	}
+	if len(missing) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Server certificate has no SAN entry for: %s", missing)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckServerCertChain(t Target) (res Result) {
+	res.Name = "Verify that the Docker server certificate chains to the configured CA"
+	chain, err := getServerChain(t)
+	if err != nil {
+		res.Skip(err.Error())
+		return
+	}
+	caPath := getDaemonOpt(t, "--tlscacert")
+	if caPath == "" {
+		res.Skip("No TLS CA certificate configured")
+		return
+	}
+	caCerts, err := readCertificates(filepath.Join(t.BaseDir, caPath))
+	if err != nil {
+		res.Skip(fmt.Sprintf("Unable to read TLS CA certificate: %v", err))
+		return
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range caCerts {
+		roots.AddCert(ca)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if _, err := chain[0].Verify(opts); err != nil {
+		output := fmt.Sprintf("Server certificate does not chain to the configured CA: %v", err)
+		res.Fail(output)
+		return
+	}
+	res.Pass()
+	return
+}
+
+// Loads the daemon's CA and server certificates and any registry certificates
+// under /etc/docker/certs.d, keyed by path
+func getCertificates(t Target) map[string][]*x509.Certificate {
+	var paths []string
+	certs := make(map[string][]*x509.Certificate)
+	for _, opt := range []string{"--tlscacert", "--tlscert"} {
+		if path := getDaemonOpt(t, opt); path != "" {
+			paths = append(paths, filepath.Join(t.BaseDir, path))
+		}
+	}
+	for _, pattern := range []string{"*/*.crt", "*/*.cert"} {
+		matches, _ := filepath.Glob(filepath.Join(t.BaseDir, "/etc/docker/certs.d", pattern))
+		paths = append(paths, matches...)
+	}
+	for _, path := range paths {
+		chain, err := readCertificates(path)
+		if err == nil {
+			certs[path] = chain
+		}
+	}
+	return certs
+}
+
+// Returns the certificate chain the daemon presents to clients, leaf first
+func getServerChain(t Target) ([]*x509.Certificate, error) {
+	certPath := getDaemonOpt(t, "--tlscert")
+	if certPath == "" {
+		return nil, fmt.Errorf("No server certificate configured")
+	}
+	chain, err := readCertificates(filepath.Join(t.BaseDir, certPath))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read server certificate: %v", err)
+	}
+	return chain, nil
+}
+
+// Parses every PEM encoded certificate in a file
+func readCertificates(path string) (certs []*x509.Certificate, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		err = fmt.Errorf("no certificates found in %s", path)
+	}
+	return
+}
+
+// Parses a PEM encoded private key and returns its public half
+func readPublicKey(path string) (interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	}
+	return key, nil
+}
+
+// Describes why a public key is considered weak, or returns "" if it is not
+func keyWeakness(key interface{}, p TLSPolicy) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if bits := k.N.BitLen(); bits < p.minRSABits() {
+			return fmt.Sprintf("RSA key of %d bits", bits)
+		}
+	case *ecdsa.PublicKey:
+		if bits := k.Curve.Params().BitSize; bits < p.minECBits() {
+			return fmt.Sprintf("%s key of %d bits", strings.ToUpper(k.Curve.Params().Name), bits)
+		}
+	case *dsa.PublicKey:
+		return "DSA key"
+	}
+	return ""
+}
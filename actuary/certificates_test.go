@@ -0,0 +1,164 @@
+package actuary
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  interface{}
+	der  []byte
+}
+
+// Creates a certificate signed by parent, or self-signed when parent is nil
+func newTestCert(t *testing.T, cn string, key interface{}, parent *testCert, notAfter time.Time) *testCert {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:              []string{cn},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	var pub interface{}
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		pub = &k.PublicKey
+	case *rsa.PrivateKey:
+		pub = &k.PublicKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, pub, signerKey)
+	if err != nil {
+		t.Fatalf("Could not create certificate: %s", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert, key, der}
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+	return key
+}
+
+// Writes the CA and server certificates under BaseDir and points the target's
+// daemon options at them
+func writeTestCerts(t *testing.T, target *Target, ca, server *testCert) {
+	testDataDir(t, target)
+	paths := map[string]string{"--tlscacert": "/ca.pem", "--tlscert": "/server-cert.pem"}
+	for opt, c := range map[string]*testCert{"--tlscacert": ca, "--tlscert": server} {
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der})
+		err := ioutil.WriteFile(filepath.Join(target.BaseDir, paths[opt]), data, 0444)
+		if err != nil {
+			t.Errorf("Could not write certificate: %s", err)
+		}
+	}
+	cmdLine, _ := target.ProcFunc("docker")
+	cmdLine = append(cmdLine, "--tlscacert="+paths["--tlscacert"], "--tlscert", paths["--tlscert"])
+	target.ProcFunc = func(procname string) (cmd []string, err error) {
+		return cmdLine, nil
+	}
+}
+
+func TestCheckCertExpirySuccess(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{""})
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", newTestKey(t), ca, time.Now().AddDate(0, 6, 0))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckCertExpiry(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Certificates valid for months, should pass.")
+}
+
+func TestCheckCertExpiryFail(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{""})
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", newTestKey(t), ca, time.Now().AddDate(0, 0, 10))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckCertExpiry(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Server certificate expires within 30 days, should not pass.")
+	testTarget.Policy.TLS.ExpiryWarningDays = 7
+	res = CheckCertExpiry(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Server certificate expires after the 7 day window, should pass.")
+}
+
+func TestCheckCertKeyStrengthFail(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{""})
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", weakKey, ca, time.Now().AddDate(1, 0, 0))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckCertKeyStrength(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Server certificate uses a 1024 bit RSA key, should not pass.")
+}
+
+func TestCheckServerCertSANFail(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{"dockerd", "-H", "tcp://docker.example.com:2376"})
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", newTestKey(t), ca, time.Now().AddDate(1, 0, 0))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckServerCertSAN(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Listen address missing from SANs, should not pass.")
+}
+
+func TestCheckServerCertSANSuccess(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{"dockerd", "--host=tcp://127.0.0.1:2376", "-H", "tcp://0.0.0.0:2376"})
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", newTestKey(t), ca, time.Now().AddDate(1, 0, 0))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckServerCertSAN(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Listen addresses covered by SANs, should pass.")
+}
+
+func TestCheckServerCertChainSuccess(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{""})
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", newTestKey(t), ca, time.Now().AddDate(1, 0, 0))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckServerCertChain(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Server certificate issued by the CA, should pass.")
+}
+
+func TestCheckServerCertChainFail(t *testing.T) {
+	testTarget, _ := NewTestTarget([]string{""})
+	ca := newTestCert(t, "ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	otherCA := newTestCert(t, "other-ca", newTestKey(t), nil, time.Now().AddDate(1, 0, 0))
+	server := newTestCert(t, "localhost", newTestKey(t), otherCA, time.Now().AddDate(1, 0, 0))
+	writeTestCerts(t, testTarget, ca, server)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckServerCertChain(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Server certificate issued by another CA, should not pass.")
+}
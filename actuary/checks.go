@@ -1,6 +1,7 @@
 package actuary
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
@@ -13,6 +14,7 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 )
@@ -70,27 +72,18 @@ var checklist = map[string]Check{
 	"audit_daemonjson":   AuditDaemonJSON,
 	"audit_containerd":   AuditContainerd,
 	"audit_runc":         AuditRunc,
-	//Docker Files
-	"docker.service_perms": CheckServicePerms,
-	"docker.service_owner": CheckServiceOwner,
-	"docker.socket_owner":  CheckSocketOwner,
-	"docker.socket_perms":  CheckSocketPerms,
-	"dockerdir_owner":      CheckDockerDirOwner,
-	"dockerdir_perms":      CheckDockerDirPerms,
-	"registrycerts_owner":  CheckRegistryCertOwner,
-	"registrycerts_perms":  CheckRegistryCertPerms,
-	"cacert_owner":         CheckCACertOwner,
-	"cacert_perms":         CheckCACertPerms,
-	"servercert_owner":     CheckServerCertOwner,
-	"servercert_perms":     CheckServerCertPerms,
-	"certkey_owner":        CheckCertKeyOwner,
-	"certkey_perms":        CheckCertKeyPerms,
-	"socket_owner":         CheckDockerSockOwner,
-	"socket_perms":         CheckDockerSockPerms,
-	"daemonjson_owner":     CheckDaemonJSONOwner,
-	"daemonjson_perms":     CheckDaemonJSONPerms,
-	"dockerdef_owner":      CheckDefaultOwner,
-	"dockerdef_perms":      CheckDefaultPerms,
+	//TLS Certificates
+	"tls_cert_expiry":  CheckCertExpiry,
+	"tls_key_strength": CheckCertKeyStrength,
+	"tls_cert_sig":     CheckCertSignature,
+	"tls_server_san":   CheckServerCertSAN,
+	"tls_server_chain": CheckServerCertChain,
+	//systemd units
+	"docker.service_tcp":   CheckServiceTCPWithoutTLS,
+	"docker.service_proxy": CheckServiceProxyCredentials,
+	"docker.socket_mode":   CheckSocketUnitMode,
+	//containerd and runc
+	"containerd_config": CheckContainerdConfig,
 	//Docker Configuration
 	"net_traffic":       RestrictNetTraffic,
 	"logging_level":     CheckLoggingLevel,
@@ -105,9 +98,27 @@ var checklist = map[string]Check{
 	"auth_plugin":       CheckAuthPlugin,
 	"central_logging":   CheckCentralLogging,
 	"legacy_registry":   CheckLegacyRegistry,
+	"live_restore":      CheckLiveRestore,
+	"userland_proxy":    CheckUserlandProxy,
+	"daemon_seccomp":    CheckDaemonSeccomp,
+	"experimental":      CheckExperimental,
+	"no_new_privileges": CheckNoNewPrivileges,
+	"default_log_opts":  CheckDefaultLogOpts,
 	//Docker Container Images
-	"root_containers": CheckContainerUser,
-	"content_trust":   CheckContentTrust,
+	"root_containers":        CheckContainerUser,
+	"content_trust":          CheckContentTrust,
+	"image_setuid":           CheckImageSetuid,
+	"image_world_writable":   CheckImageWorldWritable,
+	"image_private_keys":     CheckImagePrivateKeys,
+	"image_credential_files": CheckImageCredentialFiles,
+	"image_vulnerabilities":  CheckImageVulnerabilities,
+	"image_digest_pinning":   CheckImageDigestPinning,
+	"image_repo_digests":     CheckImageRepoDigests,
+	"image_registry":         CheckImageRegistry,
+	"image_digest_allowlist": CheckImageDigestAllowlist,
+	"image_age":              CheckImageAge,
+	"container_uptime":       CheckContainerUptime,
+	"image_outdated":         CheckOutdatedImage,
 	//Docker Container Runtime
 	"apparmor_profile":      CheckAppArmor,
 	"selinux_options":       CheckSELinux,
@@ -115,6 +126,7 @@ var checklist = map[string]Check{
 	"privileged_containers": CheckPrivContainers,
 	"sensitive_dirs":        CheckSensitiveDirs,
 	"ssh_running":           CheckSSHRunning,
+	"forbidden_processes":   CheckForbiddenProcesses,
 	"privileged_ports":      CheckPrivilegedPorts,
 	"needed_ports":          CheckNeededPorts,
 	"host_net_mode":         CheckHostNetworkMode,
@@ -130,11 +142,50 @@ var checklist = map[string]Check{
 	"mount_propagation":     CheckMountPropagation,
 	"uts_namespace":         CheckUTSnamespace,
 	"seccomp_profile":       CheckSeccompProfile,
+	"seccomp_baseline":      CheckSeccompBaseline,
 	"cgroup_usage":          CheckCgroupUsage,
 	"add_privs":             CheckAdditionalPrivs,
+	"container_health":      CheckContainerHealth,
+	"pids_limit":            CheckPidsLimit,
+	"default_bridge":        CheckDefaultBridge,
+	"userns_host":           CheckUsernsMode,
+	"docker_sock_mount":     CheckDockerSockMount,
+	"cgroupns_host":         CheckCgroupNamespace,
+	"proc_status":           CheckProcStatus,
+	"proc_lsm_label":        CheckProcLSMLabel,
+	"proc_mounts":           CheckProcMounts,
+	"network_count":         CheckContainerNetworkCount,
+	"internal_networks":     CheckInternalNetworks,
+	"shared_netns":          CheckSharedNetNamespace,
+	"tmpfs_noexec":          CheckTmpfsNoexec,
+	"host_path_volumes":     CheckHostPathVolumes,
+	"resource_policy":       CheckResourcePolicy,
+	"container_logging":     CheckContainerLogging,
+	"container_log_driver":  CheckContainerLogDriver,
+	"json_log_size":         CheckJSONLogSize,
+	//Docker Swarm Configuration
+	"swarm_managers":           CheckSwarmManagers,
+	"swarm_bind_interface":     CheckSwarmBindInterface,
+	"swarm_overlay_encryption": CheckSwarmOverlayEncryption,
+	"swarm_autolock":           CheckSwarmAutolock,
+	"swarm_node_cert_expiry":   CheckSwarmNodeCertExpiry,
+	"swarm_ca_rotation":        CheckSwarmCARotation,
+	"swarm_stale_nodes":        CheckSwarmStaleNodes,
+	"swarm_worker_isolation":   CheckSwarmWorkerIsolation,
 	//Docker Security Operations
-	"image_sprawl":     CheckImageSprawl,
-	"container_sprawl": CheckContainerSprawl,
+	"image_sprawl":         CheckImageSprawl,
+	"container_sprawl":     CheckContainerSprawl,
+	"oom_killed":           CheckOOMKilled,
+	"restart_loops":        CheckRestartLoops,
+	"unhealthy_containers": CheckUnhealthyContainers,
+	"dead_containers":      CheckDeadContainers,
+	"exited_containers":    CheckExitedContainers,
+	"dangling_images":      CheckDanglingImages,
+	"unused_images":        CheckUnusedImages,
+	"stopped_containers":   CheckStoppedContainers,
+	"unused_volumes":       CheckUnusedVolumes,
+	"unused_networks":      CheckUnusedNetworks,
+	"build_cache":          CheckBuildCache,
 }
 
 type ContainerInfo struct {
@@ -173,6 +224,34 @@ func (l *ContainerList) runCheck(r *Result, f func(c ContainerInfo) bool, msg st
 	return
 }
 
+// auditContainers runs describe on every container and fails the result with
+// msg followed by the findings of each container. Containers describe returns
+// an error for are left out, and the result is skipped with unreadable when
+// none could be described.
+func (l *ContainerList) auditContainers(r *Result, describe func(c Container) ([]string, error), msg string, unreadable string) {
+	var findings []string
+	described := 0
+	for _, c := range *l {
+		descs, err := describe(c)
+		if err != nil {
+			continue
+		}
+		described++
+		if len(descs) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, summarize(descs, 10)))
+		}
+	}
+	if described == 0 {
+		r.Skip(unreadable)
+		return
+	}
+	if len(findings) == 0 {
+		r.Pass()
+	} else {
+		r.Fail(msg + strings.Join(findings, "; "))
+	}
+}
+
 //Target stores information regarding the audit's target Docker server
 type Target struct {
 	Client     *client.Client
@@ -181,6 +260,11 @@ type Target struct {
 	ProcFunc   func(procname string) (cmd []string, err error)
 	CertPath   func(procname string, tlsOpt string) (val string)
 	BaseDir    string
+	Policy     Policy
+	// diskUsage and images are shared by the copies of the target the checks
+	// receive
+	diskUsage *diskUsageCache
+	images    *imageCache
 }
 
 //NewTarget initiates a new Target struct
@@ -197,6 +281,8 @@ func NewTarget() (a Target, err error) {
 	a.ProcFunc = getProcCmdline
 	a.CertPath = getCertPath
 	a.BaseDir = ""
+	a.diskUsage = new(diskUsageCache)
+	a.images = new(imageCache)
 	return
 }
 
@@ -277,6 +363,75 @@ func getCmdOption(args []string, opt string) (exist bool, val string) {
 	return exist, val
 }
 
+// Returns the value of a daemon setting given either as a command-line flag
+// (--name, --name=value or --name value) or as a daemon.json key. Boolean
+// flags given without a value read as "true".
+func getDaemonSetting(t Target, name string) (val string, ok bool) {
+	cmdLine, _ := t.ProcFunc("docker")
+	flag := "--" + name
+	for i, arg := range cmdLine {
+		if arg == flag {
+			if i+1 < len(cmdLine) && !strings.HasPrefix(cmdLine[i+1], "-") {
+				return cmdLine[i+1], true
+			}
+			return "true", true
+		}
+		if strings.HasPrefix(arg, flag+"=") {
+			return strings.Trim(strings.TrimPrefix(arg, flag+"="), "\""), true
+		}
+	}
+	config, err := getDaemonJSON(t)
+	if err != nil {
+		return
+	}
+	switch v := config[name].(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return
+}
+
+// Returns the addresses given to -H/--host in a daemon command line, in any of
+// the "-H addr", "-Haddr", "-H=addr", "--host addr" and "--host=addr" forms
+func parseHostFlags(args []string) (hosts []string) {
+	for i, arg := range args {
+		switch {
+		case arg == "-H" || arg == "--host":
+			if i+1 < len(args) {
+				hosts = append(hosts, args[i+1])
+			}
+		case strings.HasPrefix(arg, "--host="):
+			hosts = append(hosts, strings.TrimPrefix(arg, "--host="))
+		case strings.HasPrefix(arg, "-H"):
+			hosts = append(hosts, strings.TrimPrefix(strings.TrimPrefix(arg, "-H"), "="))
+		}
+	}
+	return
+}
+
+// Returns the addresses the daemon listens on, gathered from -H/--host flags
+// and the "hosts" key of daemon.json
+func getDaemonHosts(t Target) (hosts []string) {
+	cmdLine, _ := t.ProcFunc("docker")
+	hosts = parseHostFlags(cmdLine)
+	config, err := getDaemonJSON(t)
+	if err != nil {
+		return
+	}
+	if list, ok := config["hosts"].([]interface{}); ok {
+		for _, host := range list {
+			if h, ok := host.(string); ok {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	return
+}
+
 // Searches for a filename in given dirs
 func lookupFile(filename string, dirs []string) (info os.FileInfo, err error) {
 	for _, path := range dirs {
@@ -289,14 +444,11 @@ func lookupFile(filename string, dirs []string) (info os.FileInfo, err error) {
 	return
 }
 
+// Checks that a file sets no permission bits beyond those in safePerms
 func hasLeastPerms(info os.FileInfo, safePerms uint32) (isLeast bool,
 	perms os.FileMode) {
 	mode := info.Mode().Perm()
-	if uint32(mode) <= safePerms {
-		isLeast = true
-	} else {
-		isLeast = false
-	}
+	isLeast = uint32(mode)&^safePerms == 0
 	return isLeast, mode
 }
 
@@ -305,6 +457,7 @@ func getUserInfo(username string) (uid, gid string) {
 	userInfo, err := user.Lookup(username)
 	if err != nil {
 		log.Printf("Username %s not found", username)
+		return "", ""
 	}
 	uid = userInfo.Uid
 	gid = userInfo.Gid
@@ -327,6 +480,17 @@ func getGroupID(groupname string) string {
 	return ""
 }
 
+// Reads /etc/docker/daemon.json from the target host
+func getDaemonJSON(t Target) (config map[string]interface{}, err error) {
+	fpath := filepath.Join(t.BaseDir, "/etc/docker/daemon.json")
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(content, &config)
+	return
+}
+
 func getFileOwner(info os.FileInfo) (uid, gid string) {
 	uid = fmt.Sprint(info.Sys().(*syscall.Stat_t).Uid)
 	gid = fmt.Sprint(info.Sys().(*syscall.Stat_t).Gid)
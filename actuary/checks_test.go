@@ -1,6 +1,7 @@
 package actuary
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 )
@@ -18,6 +19,22 @@ func TestGetCmdOption(t *testing.T) {
 	}
 }
 
+func TestParseHostFlags(t *testing.T) {
+	cmdLine := []string{"dockerd", "-H", "unix:///var/run/docker.sock", "-Htcp://0.0.0.0:2375",
+		"-H=fd://", "--host", "tcp://10.0.0.1:2376", "--host=tcp://127.0.0.1:2377", "--tlsverify"}
+	hosts := parseHostFlags(cmdLine)
+	expected := []string{"unix:///var/run/docker.sock", "tcp://0.0.0.0:2375", "fd://",
+		"tcp://10.0.0.1:2376", "tcp://127.0.0.1:2377"}
+	if len(hosts) != len(expected) {
+		t.Fatalf("Expected hosts %v, got %v instead", expected, hosts)
+	}
+	for i := range expected {
+		if hosts[i] != expected[i] {
+			t.Errorf("Expected host %s, got %s instead", expected[i], hosts[i])
+		}
+	}
+}
+
 func TestLookupFile(t *testing.T) {
 	t.Log("Creating dummy file")
 
@@ -32,6 +49,40 @@ func TestLookupFile(t *testing.T) {
 	}
 }
 
+// Tests hasLeastPerms masks permission bits instead of comparing modes as numbers
+func TestHasLeastPerms(t *testing.T) {
+	f, err := ioutil.TempFile("", "perms")
+	if err != nil {
+		t.Fatalf("Could not create dummy file: %v", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	cases := []struct {
+		mode      os.FileMode
+		safePerms uint32
+		isLeast   bool
+	}{
+		{0600, 0600, true},
+		{0400, 0600, true},
+		{0644, 0644, true},
+		// Numerically below 0600, but group and others may read and write
+		{0477, 0600, false},
+		{0640, 0600, false},
+	}
+	for _, c := range cases {
+		if err := os.Chmod(f.Name(), c.mode); err != nil {
+			t.Fatalf("Could not chmod dummy file: %v", err)
+		}
+		info, err := os.Stat(f.Name())
+		if err != nil {
+			t.Fatalf("Could not stat dummy file: %v", err)
+		}
+		if isLeast, _ := hasLeastPerms(info, c.safePerms); isLeast != c.isLeast {
+			t.Errorf("Expected %v for %#o against %#o, got %v instead", c.isLeast, c.mode, c.safePerms, isLeast)
+		}
+	}
+}
+
 func TestGetCmdOutput(t *testing.T) {
 	t.Log("Executing 'echo hello'")
 	out, err := getCmdOutput("echo", "hello")
@@ -0,0 +1,182 @@
+/*
+Package checks - 3 Docker daemon configuration files (containerd and runc)
+Docker delegates container execution to containerd and runc. Their configuration,
+sockets and binaries deserve the same protection as Docker's own files.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+var binPaths = []string{"/usr/bin",
+	"/usr/local/bin",
+	"/usr/sbin",
+	"/bin",
+	"/sbin",
+}
+
+var containerdFileSpecs = []FileSpec{
+	{
+		ID:    "containerd_config_owner",
+		Name:  "Verify that /etc/containerd/config.toml ownership is set to root:root",
+		Path:  "/etc/containerd/config.toml",
+		Owner: "root",
+		Group: "root",
+	},
+	{
+		ID:    "containerd_config_perms",
+		Name:  "Verify that /etc/containerd/config.toml permissions are set to 644 or more restrictive",
+		Path:  "/etc/containerd/config.toml",
+		Perms: "0644",
+	},
+	{
+		ID:    "containerd_sock_owner",
+		Name:  "Verify that containerd socket file ownership is set to root:root",
+		Path:  "/run/containerd/containerd.sock",
+		Owner: "root",
+		Group: "root",
+	},
+	{
+		ID:    "containerd_sock_perms",
+		Name:  "Verify that containerd socket file permissions are set to 660 or more restrictive",
+		Path:  "/run/containerd/containerd.sock",
+		Perms: "0660",
+	},
+	{
+		ID:     "containerd_bin_owner",
+		Name:   "Verify that the containerd binary ownership is set to root:root",
+		Path:   "containerd",
+		Search: binPaths,
+		Owner:  "root",
+		Group:  "root",
+	},
+	{
+		ID:     "containerd_bin_perms",
+		Name:   "Verify that the containerd binary permissions are set to 755 or more restrictive",
+		Path:   "containerd",
+		Search: binPaths,
+		Perms:  "0755",
+	},
+	{
+		ID:     "runc_bin_owner",
+		Name:   "Verify that the runc binary ownership is set to root:root",
+		Path:   "runc",
+		Search: binPaths,
+		Owner:  "root",
+		Group:  "root",
+	},
+	{
+		ID:     "runc_bin_perms",
+		Name:   "Verify that the runc binary permissions are set to 755 or more restrictive",
+		Path:   "runc",
+		Search: binPaths,
+		Perms:  "0755",
+	},
+}
+
+func init() {
+	registerFileSpecs(containerdFileSpecs)
+}
+
+type containerdConfig struct {
+	GRPC struct {
+		Address    string `toml:"address"`
+		TCPAddress string `toml:"tcp_address"`
+		TCPTLSCert string `toml:"tcp_tls_cert"`
+	} `toml:"grpc"`
+	Debug struct {
+		Address string `toml:"address"`
+	} `toml:"debug"`
+	Plugins map[string]containerdCRIConfig `toml:"plugins"`
+}
+
+// Subset of the CRI plugin settings relevant to container isolation
+type containerdCRIConfig struct {
+	DisableApparmor     bool   `toml:"disable_apparmor"`
+	DisableProcMount    bool   `toml:"disable_proc_mount"`
+	StreamServerAddress string `toml:"stream_server_address"`
+	StreamServerPort    string `toml:"stream_server_port"`
+	EnableTLSStreaming  bool   `toml:"enable_tls_streaming"`
+	Containerd          struct {
+		NoPivot bool `toml:"no_pivot"`
+	} `toml:"containerd"`
+	Registry struct {
+		Configs map[string]struct {
+			TLS struct {
+				InsecureSkipVerify bool `toml:"insecure_skip_verify"`
+			} `toml:"tls"`
+		} `toml:"configs"`
+	} `toml:"registry"`
+}
+
+func CheckContainerdConfig(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that containerd configuration does not weaken isolation"
+	var config containerdConfig
+	fpath := filepath.Join(t.BaseDir, "/etc/containerd/config.toml")
+	if _, err := os.Stat(fpath); os.IsNotExist(err) {
+		res.Skip("File could not be accessed")
+		return
+	}
+	if _, err := toml.DecodeFile(fpath, &config); err != nil {
+		res.Skip(fmt.Sprintf("Unable to parse containerd configuration: %v", err))
+		return
+	}
+	if config.GRPC.TCPAddress != "" && config.GRPC.TCPTLSCert == "" {
+		findings = append(findings, fmt.Sprintf("gRPC API exposed on %s without TLS",
+			config.GRPC.TCPAddress))
+	}
+	if config.Debug.Address != "" {
+		findings = append(findings, fmt.Sprintf("debug socket enabled at %s",
+			config.Debug.Address))
+	}
+	// containerd 1.x names the plugin "cri", configuration version 2 uses its full ID
+	for _, name := range []string{"cri", "io.containerd.grpc.v1.cri"} {
+		cri, ok := config.Plugins[name]
+		if !ok {
+			continue
+		}
+		if cri.DisableApparmor {
+			findings = append(findings, "AppArmor disabled for CRI containers")
+		}
+		if cri.DisableProcMount {
+			findings = append(findings, "/proc masking disabled for CRI containers")
+		}
+		if cri.Containerd.NoPivot {
+			findings = append(findings, "no_pivot enabled, containers can escape their root filesystem")
+		}
+		if cri.StreamServerAddress != "" && !cri.EnableTLSStreaming {
+			// The address is a bare host and the port a key of its own, but a
+			// "host:port" address is accepted as well
+			host, port := cri.StreamServerAddress, cri.StreamServerPort
+			if h, p, err := net.SplitHostPort(host); err == nil {
+				host, port = h, p
+			}
+			if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+				addr := host
+				if port != "" {
+					addr = net.JoinHostPort(host, port)
+				}
+				findings = append(findings, fmt.Sprintf("streaming server on %s without TLS", addr))
+			}
+		}
+		for registry, regConfig := range cri.Registry.Configs {
+			if regConfig.TLS.InsecureSkipVerify {
+				findings = append(findings, fmt.Sprintf("TLS verification disabled for registry %s",
+					registry))
+			}
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("containerd settings weaken isolation: %s", findings)
+		res.Fail(output)
+	}
+	return
+}
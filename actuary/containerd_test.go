@@ -0,0 +1,90 @@
+package actuary
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeContainerdConfig(t *testing.T, target *Target, content string) {
+	testDataDir(t, target)
+	dir := filepath.Join(target.BaseDir, "etc/containerd")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Errorf("Could not create dir etc/containerd: %s", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "config.toml"), []byte(content), 0644)
+	if err != nil {
+		t.Errorf("Could not write temp file: %s", err)
+	}
+}
+
+func TestCheckContainerdConfigSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	writeContainerdConfig(t, testTarget, `version = 2
+
+[grpc]
+  address = "/run/containerd/containerd.sock"
+
+[plugins."io.containerd.grpc.v1.cri"]
+  stream_server_address = "127.0.0.1"
+  stream_server_port = "0"
+  disable_apparmor = false
+`)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckContainerdConfig(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Default containerd settings, should pass.")
+}
+
+func TestCheckContainerdConfigFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	writeContainerdConfig(t, testTarget, `version = 2
+
+[grpc]
+  tcp_address = "0.0.0.0:10010"
+
+[plugins."io.containerd.grpc.v1.cri"]
+  stream_server_address = "0.0.0.0"
+  stream_server_port = "10010"
+  disable_apparmor = true
+`)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckContainerdConfig(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "gRPC over TCP and AppArmor disabled, should not pass.")
+}
+
+func TestCheckContainerdConfigStreamServer(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	writeContainerdConfig(t, testTarget, `version = 2
+
+[plugins."io.containerd.grpc.v1.cri"]
+  stream_server_address = "0.0.0.0"
+  stream_server_port = "10010"
+`)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckContainerdConfig(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Streaming server on every interface without TLS, should not pass.")
+	assert.Equal(t, "containerd settings weaken isolation: [streaming server on 0.0.0.0:10010 without TLS]", res.Output)
+}
+
+func TestCheckContainerdConfigSkip(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckContainerdConfig(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No containerd configuration, should skip.")
+}
@@ -7,28 +7,32 @@ package actuary
 
 import (
 	"fmt"
-	"github.com/docker/docker/api/types"
-	"golang.org/x/net/context"
+	"github.com/docker/docker/api/types/swarm"
 	"strings"
 )
 
 func RestrictNetTraffic(t Target) (res Result) {
-	var netargs types.NetworkListOptions
+	var badNetworks []string
 	res.Name = "2.1 Restrict network traffic between containers"
-	networks, err := t.Client.NetworkList(context.TODO(), netargs)
+	networks, err := getNetworks(t)
 	if err != nil {
 		res.Skip("Cannot retrieve network list")
 		return
 	}
 	for _, network := range networks {
-		if network.Name == "bridge" {
-			if network.Options["com.docker.network.bridge.enable_icc"] == "true" {
-				res.Status = "WARN"
-				return
-			}
+		if network.Driver != "bridge" && network.Name != "bridge" {
+			continue
+		}
+		// ICC is enabled on bridges unless explicitly turned off
+		if network.Options["com.docker.network.bridge.enable_icc"] != "false" {
+			badNetworks = append(badNetworks, describeNetwork(network))
 		}
 	}
-	res.Pass()
+	if len(badNetworks) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Bridge networks with inter-container communication enabled: " + strings.Join(badNetworks, "; "))
+	}
 	return
 }
 
@@ -206,3 +210,120 @@ func CheckLegacyRegistry(t Target) (res Result) {
 	res.Fail("")
 	return res
 }
+
+func CheckLiveRestore(t Target) (res Result) {
+	res.Name = "2.14 Enable live restore"
+	if t.Info.Swarm.LocalNodeState == swarm.LocalNodeStateActive {
+		res.Skip("Live restore is not supported in swarm mode")
+		return
+	}
+	val, _ := getDaemonSetting(t, "live-restore")
+	if t.Info.LiveRestoreEnabled || val == "true" {
+		res.Pass()
+		return
+	}
+	res.Fail("Live restore is not enabled")
+	return
+}
+
+func CheckUserlandProxy(t Target) (res Result) {
+	res.Name = "2.15 Disable Userland Proxy"
+	val, _ := getDaemonSetting(t, "userland-proxy")
+	if val == "false" {
+		res.Pass()
+		return
+	}
+	res.Fail("Userland proxy is enabled")
+	return
+}
+
+func CheckDaemonSeccomp(t Target) (res Result) {
+	res.Name = "2.16 Do not override the default seccomp profile for the daemon"
+	profile, _ := getDaemonSetting(t, "seccomp-profile")
+	// Since API 1.30 SecurityOptions entries read "name=seccomp,profile=<profile>"
+	for _, opt := range t.Info.SecurityOptions {
+		fields := make(map[string]string)
+		for _, field := range strings.Split(opt, ",") {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) == 2 {
+				fields[kv[0]] = kv[1]
+			}
+		}
+		if fields["name"] == "seccomp" && profile == "" {
+			profile = fields["profile"]
+		}
+	}
+	switch profile {
+	case "", "default":
+		res.Pass()
+	case "unconfined":
+		res.Fail("Daemon runs containers without a seccomp profile by default")
+	default:
+		output := fmt.Sprintf("Daemon-wide custom seccomp profile in use: %s", profile)
+		res.Info(output)
+	}
+	return
+}
+
+func CheckExperimental(t Target) (res Result) {
+	res.Name = "2.17 Avoid experimental features in production"
+	val, _ := getDaemonSetting(t, "experimental")
+	if t.Info.ExperimentalBuild || val == "true" {
+		res.Fail("Experimental features are enabled")
+		return
+	}
+	res.Pass()
+	return
+}
+
+func CheckNoNewPrivileges(t Target) (res Result) {
+	res.Name = "2.18 Restrict containers from acquiring new privileges"
+	val, _ := getDaemonSetting(t, "no-new-privileges")
+	if val == "true" {
+		res.Pass()
+		return
+	}
+	res.Fail("Containers may acquire new privileges by default")
+	return
+}
+
+func CheckDefaultLogOpts(t Target) (res Result) {
+	res.Name = "Configure log rotation for the default logging driver"
+	driver, _ := getDaemonSetting(t, "log-driver")
+	if driver == "" {
+		driver = t.Info.LoggingDriver
+	}
+	logOpts := make(map[string]string)
+	if config, err := getDaemonJSON(t); err == nil {
+		if opts, ok := config["log-opts"].(map[string]interface{}); ok {
+			for key, val := range opts {
+				logOpts[key] = fmt.Sprint(val)
+			}
+		}
+	}
+	cmdLine, _ := t.ProcFunc("docker")
+	for i, arg := range cmdLine {
+		var opt string
+		if arg == "--log-opt" && i+1 < len(cmdLine) {
+			opt = cmdLine[i+1]
+		} else if strings.HasPrefix(arg, "--log-opt=") {
+			opt = strings.TrimPrefix(arg, "--log-opt=")
+		}
+		if kv := strings.SplitN(opt, "=", 2); len(kv) == 2 {
+			logOpts[kv[0]] = kv[1]
+		}
+	}
+	switch driver {
+	case "none":
+		res.Fail("Logging is disabled for containers by default")
+	case "json-file", "":
+		if logOpts["max-size"] == "" {
+			res.Fail("json-file logging driver has no max-size set")
+			return
+		}
+		res.Pass()
+	default:
+		res.Pass()
+	}
+	return
+}
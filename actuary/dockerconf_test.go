@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"github.com/docker/docker/api/types"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -267,3 +270,187 @@ func TestCheckLegacyRegistryFail(t *testing.T) {
 	res := CheckLegacyRegistry(*testTarget)
 	assert.Equal(t, "WARN", res.Status, "Operations on legacy registry not disabled, should not have passed.")
 }
+
+func TestCheckLiveRestoreSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{"--live-restore"})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckLiveRestore(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Live restore enabled, should have passed.")
+}
+
+func TestCheckLiveRestoreFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckLiveRestore(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Live restore not enabled, should not have passed.")
+}
+
+func TestCheckUserlandProxySuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{"--userland-proxy=false"})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckUserlandProxy(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Userland proxy disabled, should have passed.")
+}
+
+func TestCheckUserlandProxyFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	os.MkdirAll(filepath.Join(testTarget.BaseDir, "etc/docker"), 0755)
+	err = ioutil.WriteFile(filepath.Join(testTarget.BaseDir, "etc/docker/daemon.json"),
+		[]byte(`{"userland-proxy": true}`), 0644)
+	if err != nil {
+		t.Errorf("Could not write daemon.json: %s", err)
+	}
+	res := CheckUserlandProxy(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Userland proxy enabled in daemon.json, should not have passed.")
+}
+
+func TestCheckDaemonSeccompSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	testTarget.Info.SecurityOptions = []string{"name=apparmor", "name=seccomp,profile=default"}
+	res := CheckDaemonSeccomp(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Default seccomp profile in use, should have passed.")
+}
+
+func TestCheckDaemonSeccompFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{"--seccomp-profile=unconfined"})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckDaemonSeccomp(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Seccomp disabled daemon-wide, should not have passed.")
+}
+
+func TestCheckExperimentalSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckExperimental(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Experimental features off, should have passed.")
+}
+
+func TestCheckExperimentalFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	testTarget.Info.ExperimentalBuild = true
+	res := CheckExperimental(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Experimental features on, should not have passed.")
+}
+
+func TestCheckNoNewPrivilegesSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{"--no-new-privileges"})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckNoNewPrivileges(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "no-new-privileges set by default, should have passed.")
+}
+
+func TestCheckNoNewPrivilegesFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckNoNewPrivileges(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "no-new-privileges not set, should not have passed.")
+}
+
+func TestRestrictNetTrafficUserBridgeSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	var network = []types.NetworkResource{
+		{Name: "bridge", Driver: "bridge",
+			Options: map[string]string{"com.docker.network.bridge.enable_icc": "false"}},
+		{Name: "backend", Driver: "bridge",
+			Options: map[string]string{"com.docker.network.bridge.enable_icc": "false"}},
+	}
+	nJSON, err := json.Marshal(network)
+	if err != nil {
+		t.Errorf("Could not convert network to json.")
+	}
+	p := callPairing{"/networks", nJSON}
+	ts := testTarget.testServer(t, p)
+	res := RestrictNetTraffic(*testTarget)
+	defer ts.Close()
+	assert.Equal(t, "PASS", res.Status, "ICC disabled on all bridges, should pass")
+}
+
+func TestRestrictNetTrafficUserBridgeFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	var network = []types.NetworkResource{
+		{Name: "bridge", Driver: "bridge",
+			Options: map[string]string{"com.docker.network.bridge.enable_icc": "false"}},
+		{Name: "backend", Driver: "bridge"},
+	}
+	nJSON, err := json.Marshal(network)
+	if err != nil {
+		t.Errorf("Could not convert network to json.")
+	}
+	p := callPairing{"/networks", nJSON}
+	ts := testTarget.testServer(t, p)
+	res := RestrictNetTraffic(*testTarget)
+	defer ts.Close()
+	assert.Equal(t, "WARN", res.Status, "User-defined bridge with ICC enabled, should not pass")
+}
+
+func TestCheckDefaultLogOptsSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{"--log-driver=json-file", "--log-opt", "max-size=10m"})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckDefaultLogOpts(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "json-file logs rotated, should have passed.")
+}
+
+func TestCheckDefaultLogOptsFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	testTarget.Info.LoggingDriver = "json-file"
+	res := CheckDefaultLogOpts(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "json-file logs never rotated, should not have passed.")
+}
@@ -8,441 +8,297 @@ package actuary
 
 import (
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
-func CheckServiceOwner(t Target) (res Result) {
-	res.Name = "3.1 Verify that docker.service file ownership is set to root:root"
-	fileInfo, err := lookupFile("docker.service", systemdPaths)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-
-	refUID, refGID := getUserInfo("root")
-	fileUID, fileGID := getFileOwner(fileInfo)
-
-	if (refUID == fileUID) && (refGID == fileGID) {
-		res.Pass()
-	} else {
-		output := fmt.Sprintf("User/group owner should be : %s", "root")
-		res.Fail(output)
-	}
-	return
+// FileSpec describes the expected ownership and permissions of a file, a set of
+// files matched by a glob, or every file below a directory. Profiles can define
+// their own specs; each one becomes a check named after its ID.
+type FileSpec struct {
+	ID   string
+	Name string
+	// Path is an absolute path or glob, resolved relative to the target's BaseDir
+	Path string
+	// Search lists directories in which Path is looked up when it is a bare filename
+	Search []string
+	// DaemonOpt names a daemon option (e.g. "--tlscacert") whose value is the path
+	DaemonOpt string
+	Owner     string
+	Group     string
+	// Perms holds the maximum permission bits in octal, e.g. "0644"
+	Perms     string
+	Recursive bool
 }
 
-func CheckServicePerms(t Target) (res Result) {
-	res.Name = `3.2 Verify that docker.service file permissions are set to
-		644 or more restrictive`
-	var refPerms uint32 = 0644
-	fileInfo, err := lookupFile("docker.service", systemdPaths)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-	}
-	return res
+var fileSpecs = []FileSpec{
+	{
+		ID:     "docker.service_owner",
+		Name:   "3.1 Verify that docker.service file ownership is set to root:root",
+		Path:   "docker.service",
+		Search: systemdPaths,
+		Owner:  "root",
+		Group:  "root",
+	},
+	{
+		ID:     "docker.service_perms",
+		Name:   "3.2 Verify that docker.service file permissions are set to 644 or more restrictive",
+		Path:   "docker.service",
+		Search: systemdPaths,
+		Perms:  "0644",
+	},
+	{
+		ID:     "docker.socket_owner",
+		Name:   "3.3 Verify that docker.socket file ownership is set to root:root",
+		Path:   "docker.socket",
+		Search: systemdPaths,
+		Owner:  "root",
+		Group:  "root",
+	},
+	{
+		ID:     "docker.socket_perms",
+		Name:   "3.4 Verify that docker.socket file permissions are set to 644 or more restrictive",
+		Path:   "docker.socket",
+		Search: systemdPaths,
+		Perms:  "0644",
+	},
+	{
+		ID:    "dockerdir_owner",
+		Name:  "3.5 Verify that /etc/docker directory ownership is set to root:root",
+		Path:  "/etc/docker",
+		Owner: "root",
+		Group: "root",
+	},
+	{
+		ID:    "dockerdir_perms",
+		Name:  "3.6 Verify that /etc/docker directory permissions are set to 755 or more restrictive",
+		Path:  "/etc/docker",
+		Perms: "0755",
+	},
+	{
+		ID:        "registrycerts_owner",
+		Name:      "3.7 Verify that registry certificate file ownership is set to root:root",
+		Path:      "/etc/docker/certs.d",
+		Owner:     "root",
+		Group:     "root",
+		Recursive: true,
+	},
+	{
+		ID:        "registrycerts_perms",
+		Name:      "3.8 Verify that registry certificate file permissions are set to 444 or more restrictive",
+		Path:      "/etc/docker/certs.d",
+		Perms:     "0444",
+		Recursive: true,
+	},
+	{
+		ID:        "cacert_owner",
+		Name:      "3.9 Verify that TLS CA certificate file ownership is set to root:root",
+		DaemonOpt: "--tlscacert",
+		Owner:     "root",
+		Group:     "root",
+	},
+	{
+		ID:        "cacert_perms",
+		Name:      "3.10 Verify that TLS CA certificate file permissions are set to 444 or more restrictive",
+		DaemonOpt: "--tlscacert",
+		Perms:     "0444",
+	},
+	{
+		ID:        "servercert_owner",
+		Name:      "3.11 Verify that Docker server certificate file ownership is set to root:root",
+		DaemonOpt: "--tlscert",
+		Owner:     "root",
+		Group:     "root",
+	},
+	{
+		ID:        "servercert_perms",
+		Name:      "3.12 Verify that Docker server certificate file permissions are set to 444 or more restrictive",
+		DaemonOpt: "--tlscert",
+		Perms:     "0444",
+	},
+	{
+		ID:        "certkey_owner",
+		Name:      "3.13 Verify that Docker server certificate key file ownership is set to root:root",
+		DaemonOpt: "--tlskey",
+		Owner:     "root",
+		Group:     "root",
+	},
+	{
+		ID:        "certkey_perms",
+		Name:      "3.14 Verify that Docker server certificate key file permissions are set to 400",
+		DaemonOpt: "--tlskey",
+		Perms:     "0400",
+	},
+	{
+		ID:    "socket_owner",
+		Name:  "3.15 Verify that Docker socket file ownership is set to root:docker",
+		Path:  "/var/run/docker.sock",
+		Owner: "root",
+		Group: "docker",
+	},
+	{
+		ID:    "socket_perms",
+		Name:  "3.16 Verify that Docker socket file permissions are set to 660 or more restrictive",
+		Path:  "/var/run/docker.sock",
+		Perms: "0660",
+	},
+	{
+		ID:    "daemonjson_owner",
+		Name:  "3.17 Verify that daemon.json file ownership is set to root:root",
+		Path:  "/etc/docker/daemon.json",
+		Owner: "root",
+		Group: "root",
+	},
+	{
+		ID:    "daemonjson_perms",
+		Name:  "3.18 Verify that daemon.json file permissions are set to 644 or more restrictive",
+		Path:  "/etc/docker/daemon.json",
+		Perms: "0644",
+	},
+	{
+		ID:    "dockerdef_owner",
+		Name:  "3.19 Verify that /etc/default/docker file ownership is set to root:root",
+		Path:  "/etc/default/docker",
+		Owner: "root",
+		Group: "root",
+	},
+	{
+		ID:    "dockerdef_perms",
+		Name:  "3.20 Verify that /etc/default/docker file permissions are set to 644 or more restrictive",
+		Path:  "/etc/default/docker",
+		Perms: "0644",
+	},
 }
 
-func CheckSocketOwner(t Target) (res Result) {
-	res.Name = "3.3 Verify that docker.socket file ownership is set to root:root"
-	fileInfo, err := lookupFile("docker.socket", systemdPaths)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	refUID, refGID := getUserInfo("root")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGID == fileGID) {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("User/group owner should be : %s", "root")
-	}
-	return res
+func init() {
+	registerFileSpecs(fileSpecs)
 }
 
-func CheckSocketPerms(t Target) (res Result) {
-	res.Name = `3.4 Verify that docker.socket file permissions are set to 644 or more
-        restrictive`
-	var refPerms uint32 = 0644
-	fileInfo, err := lookupFile("docker.socket", systemdPaths)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
+// registerFileSpecs adds a check to the checklist for each spec
+func registerFileSpecs(specs []FileSpec) {
+	for _, spec := range specs {
+		checklist[spec.ID] = spec.Audit
 	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-	}
-	return res
 }
 
-func CheckDockerDirOwner(t Target) (res Result) {
-	res.Name = "3.5 Verify that /etc/docker directory ownership is set to root:root "
-	fileInfo, err := os.Stat("/etc/docker")
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
+// Audit verifies every file matched by the spec against its expected owner,
+// group and permissions
+func (s FileSpec) Audit(t Target) (res Result) {
+	res.Name = s.Name
+	var maxPerms uint64
+	if s.Perms != "" {
+		var err error
+		maxPerms, err = strconv.ParseUint(s.Perms, 8, 32)
+		if err != nil {
+			res.Skip(fmt.Sprintf("Invalid permissions in file spec: %s", s.Perms))
+			return
+		}
 	}
-	refUID, refGid := getUserInfo("root")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("User/group owner should be : %s", "root")
+	var refUID, refGID string
+	if s.Owner != "" {
+		refUID, _ = getUserInfo(s.Owner)
+		if refUID == "" {
+			res.Skip(fmt.Sprintf("Unknown user: %s", s.Owner))
+			return
+		}
 	}
-	return res
-}
-
-func CheckDockerDirPerms(t Target) (res Result) {
-	res.Name = `3.6 Verify that /etc/docker directory permissions
-		are set to 755 or more restrictive`
-	var refPerms uint32 = 0644
-	fileInfo, err := os.Stat("/etc/docker")
-	if os.IsNotExist(err) {
+	if s.Group != "" {
+		refGID = getGroupID(s.Group)
+		if refGID == "" {
+			res.Skip(fmt.Sprintf("Unknown group: %s", s.Group))
+			return
+		}
+	}
+	files := s.resolve(t)
+	if len(files) == 0 {
 		res.Skip("File could not be accessed")
 		return
 	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-	}
-	return res
-}
-
-func CheckRegistryCertOwner(t Target) (res Result) {
-	var badFiles []string
-	res.Name = `3.7 Verify that registry certificate file ownership
-	 is set to root:root`
-	refUID, refGid := getUserInfo("root")
-	files, err := ioutil.ReadDir("/etc/docker/cert.d")
-	if err != nil {
-		res.Status = "INFO"
-		res.Output = fmt.Sprintf("Directory is inaccessible")
-		return res
-	}
+	var badOwners, badPerms []string
 	for _, file := range files {
-		fmt.Println(file.Name())
-		if file.IsDir() {
-			certs, err := ioutil.ReadDir(file.Name())
-			if err != nil {
-				log.Fatal(err)
-			}
-			for _, cert := range certs {
-				if err != nil {
-					log.Fatal(err)
-				}
-				fileUID, fileGID := getFileOwner(cert)
-				if (refUID != fileUID) || (refGid != fileGID) {
-					badFiles = append(badFiles, cert.Name())
-				}
-			}
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
 		}
-	}
-	if len(badFiles) == 0 {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("Certificate files do not have %s as owner : %s",
-			"root", badFiles)
-	}
-	return res
-}
-
-func CheckRegistryCertPerms(t Target) (res Result) {
-	var badFiles []string
-	var refPerms uint32
-	res.Name = `3.8 Verify that registry certificate file permissions
-		are set to 444 or more restrictive`
-	refPerms = 0444
-	files, err := ioutil.ReadDir("/etc/docker/certs.d/")
-	if err != nil {
-		res.Status = "INFO"
-		res.Output = fmt.Sprintf("Directory is inaccessible")
-		return res
-	}
-	for _, file := range files {
-		fmt.Println(file.Name())
-		if file.IsDir() {
-			certs, err := ioutil.ReadDir(file.Name())
-			if err != nil {
-				log.Fatal(err)
-			}
-			for _, cert := range certs {
-				if err != nil {
-					log.Fatal(err)
-				}
-				isLeast, _ := hasLeastPerms(cert, refPerms)
-				if isLeast == false {
-					badFiles = append(badFiles, cert.Name())
-				}
+		fileUID, fileGID := getFileOwner(info)
+		if (refUID != "" && refUID != fileUID) || (refGID != "" && refGID != fileGID) {
+			badOwners = append(badOwners, fmt.Sprintf("%s (%s:%s)", file, fileUID, fileGID))
+		}
+		if s.Perms != "" {
+			isLeast, perms := hasLeastPerms(info, uint32(maxPerms))
+			if !isLeast {
+				badPerms = append(badPerms, fmt.Sprintf("%s (%#o)", file, perms))
 			}
 		}
 	}
-	if len(badFiles) == 0 {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("Certificate files do not have required permissions: %s",
-			badFiles)
-	}
-	return res
-}
-
-func CheckCACertOwner(t Target) (res Result) {
-	res.Name = "3.9 Verify that TLS CA certificate file ownership is set to root:root"
-	certPath := t.CertPath("docker", "--tlscacert")
-	fileInfo, err := os.Stat(certPath)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	refUID, refGid := getUserInfo("root")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("User/group owner should be : %s", "root")
-	}
-	return res
-}
-
-func CheckCACertPerms(t Target) (res Result) {
-	res.Name = `3.10 Verify that TLS CA certificate file permissions
-	are set to 444 or more restrictive`
-	var refPerms uint32 = 0644
-	certPath := t.CertPath("docker", "--tlscacert")
-	fileInfo, err := os.Stat(certPath)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-	}
-	return res
-}
-
-func CheckServerCertOwner(t Target) (res Result) {
-	res.Name = `3.11 Verify that Docker server certificate file ownership is set to
-        root:root`
-	certPath := t.CertPath("docker", "--tlscert")
-	fileInfo, err := os.Stat(certPath)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	refUID, refGid := getUserInfo("root")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("User/group owner should be : %s", "root")
-	}
-	return res
-}
-
-func CheckServerCertPerms(t Target) (res Result) {
-	res.Name = `3.12 Verify that Docker server certificate file permissions
-		are set to 444 or more restrictive`
-	certPath := t.CertPath("docker", "--tlscert")
-	var refPerms uint32 = 0644
-	fileInfo, err := os.Stat(certPath)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-	}
-	return res
-}
-
-func CheckCertKeyOwner(t Target) (res Result) {
-	res.Name = `3.13 Verify that Docker server certificate key file ownership is set to
-        root:root`
-	certPath := t.CertPath("docker", "--tlskey")
-	fileInfo, err := os.Stat(certPath)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	refUID, refGid := getUserInfo("root")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("User/group owner should be : %s", "root")
-	}
-	return res
-}
-
-func CheckCertKeyPerms(t Target) (res Result) {
-	res.Name = `3.14 Verify that Docker server certificate key file
-	permissions are set to 400`
-	var refPerms uint32 = 0644
-	certPath := t.CertPath("docker", "--tlskey")
-	fileInfo, err := os.Stat(certPath)
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-	}
-	return res
-}
-
-func CheckDockerSockOwner(t Target) (res Result) {
-	res.Name = `3.15 Verify that Docker socket file ownership
-	is set to root:docker`
-	fileInfo, err := os.Stat("/var/run/docker.sock")
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	refUID, _ := getUserInfo("root")
-	refGid := getGroupID("docker")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("User/group owner should be : %s", "docker")
-	}
-	return res
-}
-
-func CheckDockerSockPerms(t Target) (res Result) {
-	res.Name = `3.16 Verify that Docker socket file permissions are set to 660`
-	fileInfo, err := os.Stat("/var/run/docker.sock")
-	var refPerms uint32 = 0644
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Status = "PASS"
-	} else {
-		res.Status = "WARN"
-		res.Output = fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
+	var output []string
+	if len(badOwners) != 0 {
+		output = append(output, fmt.Sprintf("User/group owner should be %s:%s: %s",
+			s.Owner, s.Group, badOwners))
 	}
-	return res
-}
-
-func CheckDaemonJSONOwner(t Target) (res Result) {
-	res.Name = `3.17 Verify that daemon.json file ownership is set to root:root`
-	fileInfo, err := os.Stat("/etc/docker/daemon.json")
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
+	if len(badPerms) != 0 {
+		output = append(output, fmt.Sprintf("Files have less restrictive permissions than %s: %s",
+			s.Perms, badPerms))
 	}
-	refUID, _ := getUserInfo("root")
-	refGid := getGroupID("docker")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
+	if len(output) == 0 {
 		res.Pass()
 	} else {
-		output := fmt.Sprintf("User/group owner should be : %s", "docker")
-		res.Fail(output)
+		res.Fail(strings.Join(output, "; "))
 	}
 	return
 }
 
-func CheckDaemonJSONPerms(t Target) (res Result) {
-	res.Name = `3.18 Verify that daemon.json file permissions are set to 644 or more
-restrictive`
-	fileInfo, err := os.Stat("/etc/docker/daemon.json")
-	var refPerms uint32 = 0644
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Pass()
-	} else {
-		output := fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-		res.Fail(output)
+// resolve returns the list of existing files the spec applies to
+func (s FileSpec) resolve(t Target) (files []string) {
+	var pattern string
+	switch {
+	case s.DaemonOpt != "":
+		pattern = getDaemonOpt(t, s.DaemonOpt)
+		if pattern == "" {
+			return
+		}
+	case len(s.Search) != 0:
+		for _, dir := range s.Search {
+			fullPath := filepath.Join(t.BaseDir, dir, s.Path)
+			if _, err := os.Stat(fullPath); err == nil {
+				pattern = filepath.Join(dir, s.Path)
+				break
+			}
+		}
+		if pattern == "" {
+			return
+		}
+	default:
+		pattern = s.Path
 	}
-	return
-}
-
-func CheckDefaultOwner(t Target) (res Result) {
-	res.Name = `3.19 Verify that /etc/default/docker file ownership is set to root:root`
-	fileInfo, err := os.Stat("/etc/default/docker")
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
+	matches, err := filepath.Glob(filepath.Join(t.BaseDir, pattern))
+	if err != nil {
 		return
 	}
-	refUID, _ := getUserInfo("root")
-	refGid := getGroupID("docker")
-	fileUID, fileGID := getFileOwner(fileInfo)
-	if (refUID == fileUID) && (refGid == fileGID) {
-		res.Pass()
-	} else {
-		output := fmt.Sprintf("User/group owner should be : %s", "docker")
-		res.Fail(output)
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if !s.Recursive || !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+		filepath.Walk(match, func(path string, fi os.FileInfo, err error) error {
+			if err == nil && !fi.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
 	}
 	return
 }
 
-func CheckDefaultPerms(t Target) (res Result) {
-	res.Name = `3.20 Verify that /etc/default/docker file permissions are set to 644 or
-more restrictive`
-	fileInfo, err := os.Stat("/etc/default/docker")
-	var refPerms uint32 = 0644
-	if os.IsNotExist(err) {
-		res.Skip("File could not be accessed")
-		return
-	}
-	isLeast, perms := hasLeastPerms(fileInfo, refPerms)
-	if isLeast == true {
-		res.Pass()
-	} else {
-		output := fmt.Sprintf("File has less restrictive permissions than expected: %v",
-			perms)
-		res.Fail(output)
-	}
+// getDaemonOpt returns the value of a daemon option such as "--tlscacert",
+// see getDaemonSetting
+func getDaemonOpt(t Target, opt string) (val string) {
+	val, _ = getDaemonSetting(t, strings.TrimLeft(opt, "-"))
 	return
 }
@@ -0,0 +1,105 @@
+package actuary
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 3. Docker daemon configuration files
+func writeTestFile(t *testing.T, target *Target, path string, perms os.FileMode) {
+	fullPath := filepath.Join(target.BaseDir, path)
+	err := os.MkdirAll(filepath.Dir(fullPath), 0755)
+	if err != nil {
+		t.Errorf("Could not create dir for %s: %s", path, err)
+	}
+	err = ioutil.WriteFile(fullPath, []byte("test"), perms)
+	if err != nil {
+		t.Errorf("Could not write temp file: %s", err)
+	}
+	os.Chmod(fullPath, perms)
+}
+
+func TestFileSpecPermsSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	writeTestFile(t, testTarget, "etc/docker/daemon.json", 0640)
+	spec := FileSpec{Name: "test", Path: "/etc/docker/daemon.json", Perms: "0644"}
+	res := spec.Audit(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "File more restrictive than 0644, should pass.")
+}
+
+func TestFileSpecPermsFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	// 0611 is numerically lower than 0644 but grants execute bits
+	writeTestFile(t, testTarget, "etc/docker/daemon.json", 0611)
+	spec := FileSpec{Name: "test", Path: "/etc/docker/daemon.json", Perms: "0644"}
+	res := spec.Audit(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "File grants bits outside 0644, should not pass.")
+}
+
+func TestFileSpecRecursiveFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	writeTestFile(t, testTarget, "etc/docker/certs.d/registry:5000/ca.crt", 0444)
+	writeTestFile(t, testTarget, "etc/docker/certs.d/registry:5000/client.key", 0644)
+	spec := FileSpec{Name: "test", Path: "/etc/docker/certs.d", Perms: "0444", Recursive: true}
+	res := spec.Audit(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Nested file with 0644 permissions, should not pass.")
+}
+
+func TestFileSpecSearchSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	writeTestFile(t, testTarget, "lib/systemd/system/docker.service", 0644)
+	spec := FileSpec{Name: "test", Path: "docker.service", Search: systemdPaths, Perms: "0644"}
+	res := spec.Audit(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Unit file found with 0644 permissions, should pass.")
+}
+
+func TestFileSpecMissingSkip(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	spec := FileSpec{Name: "test", Path: "/etc/docker/*.json", Perms: "0644"}
+	res := spec.Audit(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No file matches the glob, should skip.")
+}
+
+func TestFileSpecDaemonOptSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testDataDir(t, testTarget)
+	defer os.RemoveAll(testTarget.BaseDir)
+	testTarget.ProcFunc = func(procname string) (cmd []string, err error) {
+		return []string{"dockerd", "--tlscacert=/certs/ca.pem"}, nil
+	}
+	writeTestFile(t, testTarget, "certs/ca.pem", 0444)
+	spec := FileSpec{Name: "test", DaemonOpt: "--tlscacert", Perms: "0444"}
+	res := spec.Audit(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "CA certificate set to 0444, should pass.")
+}
@@ -56,6 +56,8 @@ func NewTestTarget(proc []string) (*Target, error) {
 	target := &Target{
 		Info:       types.Info{},
 		Containers: ContainerList{Container{ID: "Container_id1", Info: ContainerInfo{}}},
+		diskUsage:  new(diskUsageCache),
+		images:     new(imageCache),
 	}
 
 	target.ProcFunc = func(procname string) (cmd []string, err error) {
@@ -71,6 +73,17 @@ func NewTestTarget(proc []string) (*Target, error) {
 	return target, nil
 }
 
+// For testing functions that inspect containers: the target's only container,
+// Container_id1, has the given inspect output
+func newContainerTestTarget(t *testing.T, inspect types.ContainerJSON) *Target {
+	target, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	target.Containers[0].Info = ContainerInfo{inspect}
+	return target
+}
+
 func (target *Target) testServer(t *testing.T, pairings ...callPairing) (server *httptest.Server) {
 	var err error
 	mux := http.NewServeMux()
@@ -79,16 +92,7 @@ func (target *Target) testServer(t *testing.T, pairings ...callPairing) (server
 			fmt.Sprintf("/v1.31%s", pair.call),
 			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.Header().Set("Content-Type", "application/json")
-				param := r.URL.Query()["all"]
-				if pair.call == "/container" {
-					if len(param) > 0 {
-						w.Write(pairings[1].obj)
-					} else {
-						w.Write(pairings[0].obj)
-					}
-				} else {
-					w.Write(pair.obj)
-				}
+				w.Write(pair.obj)
 			}))
 	}
 	server = httptest.NewServer(mux)
@@ -0,0 +1,113 @@
+/*
+Package checks - 4 Container Images and Build File (image freshness)
+Images have to be rebuilt regularly to pick up security patches, and containers
+recreated to run the rebuilt images. These checks flag images and containers
+older than the profile allows, and containers left behind on a previous image
+after their tag was updated.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+	"strings"
+	"time"
+)
+
+func CheckImageAge(t Target) (res Result) {
+	res.Name = "Verify that images in use are rebuilt regularly"
+	maxAge := t.Policy.Freshness.maxImageAgeDays()
+	now := time.Now()
+	auditImageInspect(t, &res, func(img usedImage, inspect types.ImageInspect) string {
+		created, err := time.Parse(time.RFC3339Nano, inspect.Created)
+		if err != nil {
+			return ""
+		}
+		if days := daysSince(created, now); days > maxAge {
+			return fmt.Sprintf("built %d days ago", days)
+		}
+		return ""
+	})
+	if res.Status == "WARN" {
+		res.Output = fmt.Sprintf("Images older than %d days: %s", maxAge, res.Output)
+	}
+	return
+}
+
+func CheckContainerUptime(t Target) (res Result) {
+	res.Name = "Verify that containers are recreated regularly"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	maxUptime := t.Policy.Freshness.maxUptimeDays()
+	now := time.Now()
+	uptime := func(c ContainerInfo) bool {
+		if c.ContainerJSONBase == nil || c.State == nil || !c.State.Running {
+			return true
+		}
+		started, err := time.Parse(time.RFC3339Nano, c.State.StartedAt)
+		if err != nil {
+			return true
+		}
+		return daysSince(started, now) <= maxUptime
+	}
+	msg := fmt.Sprintf("Containers running for more than %d days: %%s", maxUptime)
+	t.Containers.runCheck(&res, uptime, msg)
+	return
+}
+
+func CheckOutdatedImage(t Target) (res Result) {
+	var outdated []string
+	res.Name = "Verify that containers run the newest local image for their tag"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, container := range t.Containers {
+		info := container.Info
+		if info.ContainerJSONBase == nil || info.Config == nil {
+			continue
+		}
+		ref := info.Config.Image
+		if ref == "" || strings.Contains(ref, "@") || isImageID(ref, info.Image) {
+			continue
+		}
+		// The tag may have been removed or never existed locally
+		latest, _, err := t.Client.ImageInspectWithRaw(context.TODO(), ref)
+		if err != nil || latest.ID == info.Image {
+			continue
+		}
+		running, _, err := t.Client.ImageInspectWithRaw(context.TODO(), info.Image)
+		if err != nil {
+			continue
+		}
+		latestCreated, err1 := time.Parse(time.RFC3339Nano, latest.Created)
+		runningCreated, err2 := time.Parse(time.RFC3339Nano, running.Created)
+		if err1 == nil && err2 == nil && latestCreated.After(runningCreated) {
+			outdated = append(outdated, fmt.Sprintf("%s (%s runs %s, newest is %s)",
+				container.ID, ref, shortImageID(info.Image), shortImageID(latest.ID)))
+		}
+	}
+	if len(outdated) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Containers running an outdated image: %s", outdated)
+		res.Fail(output)
+	}
+	return
+}
+
+// Whole days elapsed between two times
+func daysSince(then, now time.Time) int {
+	return int(now.Sub(then).Hours() / 24)
+}
+
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,81 @@
+package actuary
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func daysAgo(days int) string {
+	return time.Now().AddDate(0, 0, -days).Format(time.RFC3339Nano)
+}
+
+func TestCheckImageAgeSuccess(t *testing.T) {
+	testTarget, ts := imageTestTarget(t, types.ImageInspect{Created: daysAgo(10)}, nil)
+	defer ts.Close()
+	res := CheckImageAge(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Image built 10 days ago, should have passed.")
+}
+
+func TestCheckImageAgeFail(t *testing.T) {
+	testTarget, ts := imageTestTarget(t, types.ImageInspect{Created: daysAgo(10)}, nil)
+	defer ts.Close()
+	testTarget.Policy.Freshness.MaxImageAgeDays = 7
+	res := CheckImageAge(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Image older than the policy allows, should not have passed.")
+}
+
+func TestCheckContainerUptimeSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testTarget.Containers[0].Info.ContainerJSONBase = &types.ContainerJSONBase{
+		State: &types.ContainerState{Running: true, StartedAt: daysAgo(2)},
+	}
+	res := CheckContainerUptime(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container started 2 days ago, should have passed.")
+}
+
+func TestCheckContainerUptimeFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testTarget.Containers[0].Info.ContainerJSONBase = &types.ContainerJSONBase{
+		State: &types.ContainerState{Running: true, StartedAt: daysAgo(45)},
+	}
+	res := CheckContainerUptime(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container running for 45 days, should not have passed.")
+}
+
+func outdatedTestTarget(t *testing.T, latest types.ImageInspect) (*Target, func()) {
+	// The image target's server is replaced, as it has no route for the tag
+	testTarget, imageServer := imageTestTarget(t, types.ImageInspect{}, nil)
+	running, _ := json.Marshal(types.ImageInspect{ID: "sha256:abc", Created: daysAgo(30)})
+	latestJSON, _ := json.Marshal(latest)
+	ts := testTarget.testServer(t,
+		callPairing{"/images/sha256:abc/json", running},
+		callPairing{"/images/app:latest/json", latestJSON})
+	return testTarget, func() {
+		imageServer.Close()
+		ts.Close()
+	}
+}
+
+func TestCheckOutdatedImageSuccess(t *testing.T) {
+	testTarget, cleanup := outdatedTestTarget(t, types.ImageInspect{ID: "sha256:abc", Created: daysAgo(30)})
+	defer cleanup()
+	res := CheckOutdatedImage(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container runs the tagged image, should have passed.")
+}
+
+func TestCheckOutdatedImageFail(t *testing.T) {
+	testTarget, cleanup := outdatedTestTarget(t, types.ImageInspect{ID: "sha256:def", Created: daysAgo(1)})
+	defer cleanup()
+	res := CheckOutdatedImage(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Tag points to a newer image, should not have passed.")
+	assert.Equal(t, "Containers running an outdated image: [Container_id1 (app:latest runs abc, newest is def)]", res.Output)
+}
@@ -0,0 +1,112 @@
+/*
+Package checks - 6 Docker Security Operations (container health)
+Containers that crash, restart in a loop or fail their health checks are an
+operational risk, and often the first sign of an attack or a resource
+exhaustion. These checks read the state the daemon keeps for every container,
+stopped ones included.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"time"
+)
+
+func CheckOOMKilled(t Target) (res Result) {
+	res.Name = "Verify that no container was killed for running out of memory"
+	auditContainerState(t, &res, func(c Container, state *types.ContainerState) string {
+		if !state.OOMKilled {
+			return ""
+		}
+		return fmt.Sprintf("OOM killed (%s)", state.Status)
+	}, "Containers killed for running out of memory: ")
+	return
+}
+
+func CheckRestartLoops(t Target) (res Result) {
+	res.Name = "Verify that no container is restarting in a loop"
+	max := t.Policy.Health.maxRestartCount()
+	window := time.Duration(t.Policy.Health.restartWindowHours()) * time.Hour
+	now := time.Now()
+	auditContainerState(t, &res, func(c Container, state *types.ContainerState) string {
+		count := c.Info.RestartCount
+		if state.Restarting {
+			return fmt.Sprintf("restarting after %d restarts", count)
+		}
+		// The restart count is kept for the lifetime of the container, so
+		// only a container started again recently is still looping
+		started, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+		if err != nil || now.Sub(started) > window {
+			return ""
+		}
+		if count > max {
+			return fmt.Sprintf("restarted %d times, last %s ago", count, now.Sub(started).Round(time.Second))
+		}
+		return ""
+	}, fmt.Sprintf("Containers restarted more than %d times: ", max))
+	return
+}
+
+func CheckUnhealthyContainers(t Target) (res Result) {
+	res.Name = "Verify that containers pass their health checks"
+	auditContainerState(t, &res, func(c Container, state *types.ContainerState) string {
+		if state.Health == nil || state.Health.Status != types.Unhealthy {
+			return ""
+		}
+		return fmt.Sprintf("unhealthy, %d failures in a row", state.Health.FailingStreak)
+	}, "Containers failing their health checks: ")
+	return
+}
+
+func CheckDeadContainers(t Target) (res Result) {
+	res.Name = "Verify that no container is dead"
+	auditContainerState(t, &res, func(c Container, state *types.ContainerState) string {
+		if !state.Dead && state.Status != "dead" {
+			return ""
+		}
+		if state.Error != "" {
+			return "dead: " + state.Error
+		}
+		return "dead"
+	}, "Containers the daemon failed to stop or remove: ")
+	return
+}
+
+func CheckExitedContainers(t Target) (res Result) {
+	res.Name = "Verify that failed containers are not left behind"
+	maxHours := t.Policy.Health.maxExitedHours()
+	now := time.Now()
+	auditContainerState(t, &res, func(c Container, state *types.ContainerState) string {
+		if state.Status != "exited" || state.ExitCode == 0 {
+			return ""
+		}
+		finished, err := time.Parse(time.RFC3339Nano, state.FinishedAt)
+		if err != nil {
+			return ""
+		}
+		if hours := int(now.Sub(finished).Hours()); hours > maxHours {
+			return fmt.Sprintf("exited with code %d %d hours ago", state.ExitCode, hours)
+		}
+		return ""
+	}, fmt.Sprintf("Containers exited with an error for more than %d hours: ", maxHours))
+	return
+}
+
+// auditContainerState runs describe on the state of every container and fails
+// the result with msg followed by the descriptions returned
+func auditContainerState(t Target, res *Result, describe func(c Container, state *types.ContainerState) string, msg string) {
+	if !t.Containers.Running() {
+		res.Skip("No containers")
+		return
+	}
+	t.Containers.auditContainers(res, func(c Container) ([]string, error) {
+		if c.Info.ContainerJSONBase == nil || c.Info.State == nil {
+			return nil, nil
+		}
+		if desc := describe(c, c.Info.State); desc != "" {
+			return []string{desc}, nil
+		}
+		return nil, nil
+	}, msg, "No containers")
+}
@@ -0,0 +1,94 @@
+package actuary
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHealthChecksSuccess(t *testing.T) {
+	state := &types.ContainerState{
+		Status:  "running",
+		Running: true,
+		Health:  &types.Health{Status: types.Healthy},
+	}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: state, RestartCount: 1}})
+	for _, check := range []Check{CheckOOMKilled, CheckRestartLoops, CheckUnhealthyContainers,
+		CheckDeadContainers, CheckExitedContainers} {
+		res := check(*testTarget)
+		assert.Equal(t, "PASS", res.Status, "%s: healthy container, should have passed.", res.Name)
+	}
+}
+
+func TestCheckOOMKilledFail(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Status: "exited", OOMKilled: true, ExitCode: 137}}})
+	res := CheckOOMKilled(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "OOM killed container, should not have passed.")
+	assert.Equal(t, "Containers killed for running out of memory: Container_id1: OOM killed (exited)", res.Output)
+}
+
+func TestCheckRestartLoopsFail(t *testing.T) {
+	state := &types.ContainerState{
+		Status:    "running",
+		Running:   true,
+		StartedAt: time.Now().Add(-10 * time.Minute).Format(time.RFC3339Nano),
+	}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: state, RestartCount: 12}})
+	res := CheckRestartLoops(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container restarted too often, should not have passed.")
+	assert.Equal(t, "Containers restarted more than 5 times: Container_id1: restarted 12 times, last 10m0s ago", res.Output)
+
+	testTarget.Policy.Health.MaxRestartCount = 20
+	res = CheckRestartLoops(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Restarts within the policy, should have passed.")
+
+	testTarget.Policy.Health.MaxRestartCount = 0
+	state.StartedAt = time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339Nano)
+	res = CheckRestartLoops(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container running for a month since its last restart, should have passed.")
+
+	testTarget = newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Status: "restarting", Restarting: true}, RestartCount: 2}})
+	res = CheckRestartLoops(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container restarting, should not have passed.")
+}
+
+func TestCheckUnhealthyContainersFail(t *testing.T) {
+	state := &types.ContainerState{
+		Status:  "running",
+		Running: true,
+		Health:  &types.Health{Status: types.Unhealthy, FailingStreak: 3},
+	}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: state}})
+	res := CheckUnhealthyContainers(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Unhealthy container, should not have passed.")
+	assert.Equal(t, "Containers failing their health checks: Container_id1: unhealthy, 3 failures in a row", res.Output)
+}
+
+func TestCheckDeadContainersFail(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Status: "dead", Dead: true, Error: "device busy"}}})
+	res := CheckDeadContainers(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Dead container, should not have passed.")
+	assert.Equal(t, "Containers the daemon failed to stop or remove: Container_id1: dead: device busy", res.Output)
+}
+
+func TestCheckExitedContainers(t *testing.T) {
+	state := &types.ContainerState{
+		Status:     "exited",
+		ExitCode:   1,
+		FinishedAt: time.Now().Add(-50 * time.Hour).Format(time.RFC3339Nano),
+	}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: state}})
+	res := CheckExitedContainers(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container failed two days ago, should not have passed.")
+	assert.Equal(t, "Containers exited with an error for more than 24 hours: Container_id1: exited with code 1 50 hours ago", res.Output)
+
+	testTarget.Policy.Health.MaxExitedHours = 72
+	res = CheckExitedContainers(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Failure within the policy, should have passed.")
+
+	state.ExitCode = 0
+	testTarget.Policy.Health.MaxExitedHours = 0
+	res = CheckExitedContainers(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container exited cleanly, should have passed.")
+}
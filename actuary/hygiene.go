@@ -0,0 +1,199 @@
+/*
+Package checks - 6 Docker Security Operations (resource hygiene)
+Images, containers and volumes left behind keep outdated software and data on
+the host, and fill its disk. These checks read the usage the daemon reports
+for "docker system df" and compare each category with the thresholds of the
+profile, reporting the space removing the unused resources would reclaim.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
+	"golang.org/x/net/context"
+	"sync"
+)
+
+// Networks the daemon creates itself, which are never unused
+var predefinedNetworks = []string{"bridge", "host", "none", "docker_gwbridge", "ingress"}
+
+// diskUsageCache holds the disk usage of the daemon, which walks every layer
+// and volume to compute it, so that the checks fetch it only once
+type diskUsageCache struct {
+	once  sync.Once
+	usage types.DiskUsage
+	err   error
+}
+
+func CheckDanglingImages(t Target) (res Result) {
+	res.Name = "Avoid keeping dangling images"
+	auditDiskUsage(t, &res, func(du types.DiskUsage) (ids []string, reclaimable int64) {
+		for _, image := range du.Images {
+			if image != nil && isDanglingImage(*image) {
+				ids = append(ids, shortImageID(image.ID))
+				reclaimable += imageReclaimable(*image)
+			}
+		}
+		return
+	}, "dangling images", t.Policy.Hygiene.maxDanglingImages())
+	return
+}
+
+func CheckUnusedImages(t Target) (res Result) {
+	res.Name = "Avoid keeping images no container uses"
+	auditDiskUsage(t, &res, func(du types.DiskUsage) (ids []string, reclaimable int64) {
+		for _, image := range du.Images {
+			if image != nil && image.Containers == 0 {
+				ids = append(ids, imageName(*image))
+				reclaimable += imageReclaimable(*image)
+			}
+		}
+		return
+	}, "unused images", t.Policy.Hygiene.maxUnusedImages())
+	return
+}
+
+func CheckStoppedContainers(t Target) (res Result) {
+	res.Name = "Avoid keeping stopped containers"
+	auditDiskUsage(t, &res, func(du types.DiskUsage) (ids []string, reclaimable int64) {
+		for _, c := range du.Containers {
+			if c != nil && c.State != "running" && c.State != "paused" && c.State != "restarting" {
+				id := c.ID
+				if len(id) > 12 {
+					id = id[:12]
+				}
+				ids = append(ids, id)
+				reclaimable += c.SizeRw
+			}
+		}
+		return
+	}, "stopped containers", t.Policy.Hygiene.maxStoppedContainers())
+	return
+}
+
+func CheckUnusedVolumes(t Target) (res Result) {
+	res.Name = "Avoid keeping volumes no container uses"
+	auditDiskUsage(t, &res, func(du types.DiskUsage) (ids []string, reclaimable int64) {
+		for _, volume := range du.Volumes {
+			if volume == nil || volume.UsageData == nil || volume.UsageData.RefCount != 0 {
+				continue
+			}
+			ids = append(ids, volume.Name)
+			// Only the local driver reports a size, others report -1
+			if volume.UsageData.Size > 0 {
+				reclaimable += volume.UsageData.Size
+			}
+		}
+		return
+	}, "unused volumes", t.Policy.Hygiene.maxUnusedVolumes())
+	return
+}
+
+func CheckUnusedNetworks(t Target) (res Result) {
+	var unused []string
+	res.Name = "Avoid keeping networks no container is attached to"
+	networks, err := getNetworks(t)
+	if err != nil {
+		res.Skip("Cannot retrieve network list")
+		return
+	}
+	for _, network := range networks {
+		// Swarm networks only list the containers of the local node
+		if stringInSlice(network.Name, predefinedNetworks) || network.Scope == "swarm" {
+			continue
+		}
+		if len(network.Containers) == 0 {
+			unused = append(unused, network.Name)
+		}
+	}
+	max := t.Policy.Hygiene.maxUnusedNetworks()
+	if len(unused) > max {
+		output := fmt.Sprintf("%d unused networks, more than %d: %s", len(unused), max, summarize(unused, 10))
+		res.Fail(output)
+		return
+	}
+	res.Pass()
+	return
+}
+
+func CheckBuildCache(t Target) (res Result) {
+	res.Name = "Avoid a build cache growing without bounds"
+	maxSize, err := units.RAMInBytes(t.Policy.Hygiene.maxBuildCacheSize())
+	if err != nil {
+		res.Skip(fmt.Sprintf("Invalid maximum build cache size: %v", err))
+		return
+	}
+	du, err := getDiskUsage(t)
+	if err != nil {
+		res.Skip("Unable to retrieve disk usage")
+		return
+	}
+	if du.BuilderSize > maxSize {
+		output := fmt.Sprintf("Build cache uses %s, more than %s", units.BytesSize(float64(du.BuilderSize)),
+			units.BytesSize(float64(maxSize)))
+		res.Fail(output)
+		return
+	}
+	res.Pass()
+	return
+}
+
+// auditDiskUsage fails the result when the resources listed by unused from the
+// disk usage outnumber max, reporting the space they take
+func auditDiskUsage(t Target, res *Result, unused func(du types.DiskUsage) ([]string, int64), category string, max int) {
+	du, err := getDiskUsage(t)
+	if err != nil {
+		res.Skip("Unable to retrieve disk usage")
+		return
+	}
+	ids, reclaimable := unused(du)
+	if len(ids) > max {
+		output := fmt.Sprintf("%d %s, more than %d, %s reclaimable: %s", len(ids), category, max,
+			units.BytesSize(float64(reclaimable)), summarize(ids, 10))
+		res.Fail(output)
+		return
+	}
+	res.Pass()
+}
+
+// getDiskUsage returns the disk usage of the daemon, fetching it on first use
+func getDiskUsage(t Target) (types.DiskUsage, error) {
+	if t.diskUsage == nil {
+		return t.Client.DiskUsage(context.TODO())
+	}
+	t.diskUsage.once.Do(func() {
+		t.diskUsage.usage, t.diskUsage.err = t.Client.DiskUsage(context.TODO())
+	})
+	return t.diskUsage.usage, t.diskUsage.err
+}
+
+// isDanglingImage tells whether an image lost its tags, usually to a newer
+// build of the same tag
+func isDanglingImage(image types.ImageSummary) bool {
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+// imageReclaimable returns the space removing an image frees, leaving out the
+// layers it shares with other images. SharedSize is -1 when unknown.
+func imageReclaimable(image types.ImageSummary) int64 {
+	if image.SharedSize > 0 {
+		return image.Size - image.SharedSize
+	}
+	return image.Size
+}
+
+// imageName returns the first tag of an image, or its ID when untagged
+func imageName(image types.ImageSummary) string {
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" {
+			return tag
+		}
+	}
+	return shortImageID(image.ID)
+}
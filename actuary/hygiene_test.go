@@ -0,0 +1,140 @@
+package actuary
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const mib = 1024 * 1024
+
+// Serves a disk usage with two dangling images, one unused tagged image, two
+// stopped containers and two unused volumes
+func diskUsagePairing(t *testing.T) callPairing {
+	du := types.DiskUsage{
+		Images: []*types.ImageSummary{
+			{ID: "sha256:aaaaaaaaaaaaaaaa", RepoTags: []string{"<none>:<none>"}, Size: 100 * mib, SharedSize: 40 * mib},
+			{ID: "sha256:bbbbbbbbbbbbbbbb", Size: 50 * mib, SharedSize: -1},
+			{ID: "sha256:cccccccccccccccc", RepoTags: []string{"nginx:latest"}, Size: 30 * mib, Containers: 1},
+			{ID: "sha256:dddddddddddddddd", RepoTags: []string{"redis:4"}, Size: 20 * mib},
+		},
+		Containers: []*types.Container{
+			{ID: "0123456789abcdef", State: "running", SizeRw: mib},
+			{ID: "fedcba9876543210", State: "exited", SizeRw: 2 * mib},
+			{ID: "00000000000000ff", State: "created"},
+		},
+		Volumes: []*types.Volume{
+			{Name: "data", UsageData: &types.VolumeUsageData{RefCount: 1, Size: 10 * mib}},
+			{Name: "old", UsageData: &types.VolumeUsageData{RefCount: 0, Size: 5 * mib}},
+			{Name: "remote", UsageData: &types.VolumeUsageData{RefCount: 0, Size: -1}},
+		},
+		BuilderSize: 3 * 1024 * mib,
+	}
+	duJSON, err := json.Marshal(du)
+	if err != nil {
+		t.Errorf("Could not convert disk usage to json.")
+	}
+	return callPairing{"/system/df", duJSON}
+}
+
+func TestHygieneChecksSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	ts := testTarget.testServer(t, diskUsagePairing(t))
+	defer ts.Close()
+	for _, check := range []Check{CheckDanglingImages, CheckUnusedImages, CheckStoppedContainers,
+		CheckUnusedVolumes, CheckBuildCache} {
+		res := check(*testTarget)
+		assert.Equal(t, "PASS", res.Status, "%s: within the default thresholds, should pass.", res.Name)
+	}
+}
+
+func TestHygieneChecksFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	ts := testTarget.testServer(t, diskUsagePairing(t))
+	defer ts.Close()
+	testTarget.Policy.Hygiene = HygienePolicy{
+		MaxDanglingImages:    1,
+		MaxUnusedImages:      2,
+		MaxStoppedContainers: 1,
+		MaxUnusedVolumes:     1,
+		MaxBuildCacheSize:    "1g",
+	}
+	res := CheckDanglingImages(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Too many dangling images, should not pass.")
+	assert.Equal(t, "2 dangling images, more than 1, 110MiB reclaimable: aaaaaaaaaaaa, bbbbbbbbbbbb", res.Output)
+
+	res = CheckUnusedImages(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Too many unused images, should not pass.")
+	assert.Equal(t, "3 unused images, more than 2, 130MiB reclaimable: aaaaaaaaaaaa, bbbbbbbbbbbb, redis:4", res.Output)
+
+	res = CheckStoppedContainers(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Too many stopped containers, should not pass.")
+	assert.Equal(t, "2 stopped containers, more than 1, 2MiB reclaimable: fedcba987654, 000000000000", res.Output)
+
+	res = CheckUnusedVolumes(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Too many unused volumes, should not pass.")
+	assert.Equal(t, "2 unused volumes, more than 1, 5MiB reclaimable: old, remote", res.Output)
+
+	res = CheckBuildCache(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Build cache too large, should not pass.")
+	assert.Equal(t, "Build cache uses 3GiB, more than 1GiB", res.Output)
+}
+
+func TestHygieneChecksFetchDiskUsageOnce(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	calls := 0
+	pair := diskUsagePairing(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(pair.obj)
+	}))
+	defer ts.Close()
+	testTarget.Client, err = client.NewClient(ts.URL, api.DefaultVersion, nil, nil)
+	if err != nil {
+		t.Errorf("Could not manipulate test target client.")
+	}
+	for _, check := range []Check{CheckDanglingImages, CheckUnusedImages, CheckStoppedContainers,
+		CheckUnusedVolumes, CheckBuildCache} {
+		check(*testTarget)
+	}
+	assert.Equal(t, 1, calls, "Disk usage should be fetched once for all the checks.")
+}
+
+func TestCheckUnusedNetworks(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	networks := []types.NetworkResource{
+		{Name: "bridge", ID: "n1", Scope: "local"},
+		{Name: "frontend", ID: "n2", Scope: "local",
+			Containers: map[string]types.EndpointResource{"c1": {Name: "web"}}},
+		{Name: "legacy", ID: "n3", Scope: "local"},
+		{Name: "test", ID: "n5", Scope: "local"},
+		{Name: "overlay", ID: "n4", Scope: "swarm"},
+	}
+	ts := testTarget.testServer(t, networkTestPairings(t, networks)...)
+	defer ts.Close()
+	res := CheckUnusedNetworks(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Within the default threshold, should pass.")
+
+	testTarget.Policy.Hygiene.MaxUnusedNetworks = 1
+	res = CheckUnusedNetworks(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Too many unused networks, should not pass.")
+	assert.Equal(t, "2 unused networks, more than 1: legacy, test", res.Output)
+}
@@ -0,0 +1,328 @@
+/*
+Package checks - 4 Container Images and Build File (image contents)
+Some recommendations can only be verified by looking inside an image. Images are
+exported with ImageSave and their layers replayed, whiteouts included, to recover
+the filesystem a container starts with.
+*/
+package actuary
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Only files up to this size are read when looking for private keys
+	maxKeyFileSize = 64 * 1024
+	// Upper bound on the size of the files whose content is kept
+	maxCapturedFileSize = 32 * 1024 * 1024
+)
+
+// imageFile is the metadata kept for each file of an image
+type imageFile struct {
+	Mode os.FileMode
+	Size int64
+	// PrivateKey is set for files holding a PEM encoded private key
+	PrivateKey bool
+	// Content is only kept for files later checks parse, see isCapturedFile
+	Content []byte
+}
+
+// imageFS maps absolute paths to the files visible in a container started from an image
+type imageFS map[string]imageFile
+
+// imageLayer holds the files a layer adds and the paths it removes
+type imageLayer struct {
+	Files     map[string]imageFile
+	Whiteouts []string
+	Opaque    []string
+}
+
+var imageFSCache = struct {
+	sync.Mutex
+	images map[string]imageFS
+}{images: make(map[string]imageFS)}
+
+var credentialFiles = []string{".netrc", ".git-credentials", ".pgpass", ".npmrc", ".pypirc",
+	".htpasswd", ".dockercfg", ".docker/config.json", ".aws/credentials", ".kube/config"}
+
+func CheckImageSetuid(t Target) (res Result) {
+	res.Name = "4.8 Remove setuid and setgid permissions in the images"
+	auditImageFiles(t, &res, func(name string, file imageFile) string {
+		if !file.Mode.IsRegular() || stringInSlice(name, t.Policy.Images.AllowedSetuid) {
+			return ""
+		}
+		switch {
+		case file.Mode&os.ModeSetuid != 0:
+			return name + " (setuid)"
+		case file.Mode&os.ModeSetgid != 0:
+			return name + " (setgid)"
+		}
+		return ""
+	})
+	return
+}
+
+func CheckImageWorldWritable(t Target) (res Result) {
+	res.Name = "Verify that images contain no world-writable files"
+	auditImageFiles(t, &res, func(name string, file imageFile) string {
+		// Symlink permissions are meaningless and sticky directories such as
+		// /tmp are meant to be shared
+		if file.Mode&os.ModeSymlink != 0 || file.Mode.Perm()&0002 == 0 ||
+			(file.Mode.IsDir() && file.Mode&os.ModeSticky != 0) {
+			return ""
+		}
+		return name
+	})
+	return
+}
+
+func CheckImagePrivateKeys(t Target) (res Result) {
+	res.Name = "Verify that images contain no private keys"
+	auditImageFiles(t, &res, func(name string, file imageFile) string {
+		if file.PrivateKey {
+			return name
+		}
+		return ""
+	})
+	return
+}
+
+func CheckImageCredentialFiles(t Target) (res Result) {
+	res.Name = "Verify that images contain no credential or .env files"
+	auditImageFiles(t, &res, func(name string, file imageFile) string {
+		if file.Mode.IsRegular() && isCredentialFile(name) {
+			return name
+		}
+		return ""
+	})
+	return
+}
+
+// auditImageFiles scans every image in use and fails the result with the
+// descriptions returned for matching files
+func auditImageFiles(t Target, res *Result, describe func(name string, file imageFile) string) {
+	var findings []string
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	scanned := 0
+	for _, img := range getImagesInUse(t) {
+		fs, err := scanImage(t, img.ID)
+		if err != nil {
+			continue
+		}
+		scanned++
+		var matches []string
+		for _, name := range fs.paths() {
+			if desc := describe(name, fs[name]); desc != "" {
+				matches = append(matches, desc)
+			}
+		}
+		if len(matches) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", img.Ref, summarize(matches, 10)))
+		}
+	}
+	if scanned == 0 {
+		res.Skip("Unable to read image contents")
+		return
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail(strings.Join(findings, "; "))
+	}
+}
+
+// scanImage returns the filesystem of an image. Images are only exported the
+// first time their digest is seen.
+func scanImage(t Target, id string) (imageFS, error) {
+	imageFSCache.Lock()
+	fs, ok := imageFSCache.images[id]
+	imageFSCache.Unlock()
+	if ok {
+		return fs, nil
+	}
+	reader, err := t.Client.ImageSave(context.TODO(), []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	fs, err = readImageArchive(reader)
+	if err != nil {
+		return nil, err
+	}
+	imageFSCache.Lock()
+	imageFSCache.images[id] = fs
+	imageFSCache.Unlock()
+	return fs, nil
+}
+
+// readImageArchive reads the output of ImageSave in a single pass. Layers can
+// precede manifest.json in the stream, so each one is kept until the manifest
+// tells the order in which they apply.
+func readImageArchive(r io.Reader) (imageFS, error) {
+	var manifest []struct {
+		Layers []string
+	}
+	layers := make(map[string]*imageLayer)
+	archive := tar.NewReader(r)
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		switch {
+		case name == "manifest.json":
+			if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest.json: %v", err)
+			}
+		case strings.HasSuffix(name, ".tar") || strings.HasPrefix(name, "blobs/"):
+			// Blobs also hold image configs and indexes; anything that does
+			// not read as a tar archive is not a layer
+			if layer, err := readLayer(archive); err == nil {
+				layers[name] = layer
+			}
+		}
+	}
+	if len(manifest) == 0 {
+		return nil, fmt.Errorf("no manifest found in image archive")
+	}
+	fs := make(imageFS)
+	for _, name := range manifest[0].Layers {
+		layer, ok := layers[path.Clean(name)]
+		if !ok {
+			return nil, fmt.Errorf("layer %s missing from image archive", name)
+		}
+		layer.applyTo(fs)
+	}
+	return fs, nil
+}
+
+// readLayer reads the file metadata of a layer, which may be gzip compressed
+func readLayer(r io.Reader) (*imageLayer, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		src = gz
+	}
+	layer := &imageLayer{Files: make(map[string]imageFile)}
+	archive := tar.NewReader(src)
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			return layer, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Join("/", hdr.Name)
+		dir, base := path.Split(name)
+		if base == ".wh..wh..opq" {
+			layer.Opaque = append(layer.Opaque, path.Clean(dir))
+			continue
+		}
+		if strings.HasPrefix(base, ".wh.") {
+			layer.Whiteouts = append(layer.Whiteouts, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+			continue
+		}
+		info := hdr.FileInfo()
+		file := imageFile{Mode: info.Mode(), Size: hdr.Size}
+		captured := isCapturedFile(name) && hdr.Size <= maxCapturedFileSize
+		if info.Mode().IsRegular() && (hdr.Size <= maxKeyFileSize || captured) {
+			data, err := ioutil.ReadAll(archive)
+			if err != nil {
+				return nil, err
+			}
+			file.PrivateKey = bytes.Contains(data, []byte("PRIVATE KEY-----"))
+			if captured {
+				file.Content = data
+			}
+		}
+		layer.Files[name] = file
+	}
+}
+
+// applyTo replays a layer on top of the layers below it. Whiteouts only hide
+// files from lower layers, so they are applied before the layer's own files.
+func (l *imageLayer) applyTo(fs imageFS) {
+	for _, dir := range l.Opaque {
+		fs.removeChildren(dir)
+	}
+	for _, name := range l.Whiteouts {
+		delete(fs, name)
+		fs.removeChildren(name)
+	}
+	for name, file := range l.Files {
+		fs[name] = file
+	}
+}
+
+func (fs imageFS) removeChildren(dir string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for name := range fs {
+		if strings.HasPrefix(name, prefix) {
+			delete(fs, name)
+		}
+	}
+}
+
+// paths returns the paths in the filesystem in lexical order
+func (fs imageFS) paths() (names []string) {
+	for name := range fs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Credential stores of common tools, plus .env files other than templates
+func isCredentialFile(name string) bool {
+	for _, cred := range credentialFiles {
+		if strings.HasSuffix(name, "/"+cred) {
+			return true
+		}
+	}
+	base := path.Base(name)
+	if base != ".env" && !strings.HasPrefix(base, ".env.") {
+		return false
+	}
+	switch strings.TrimPrefix(base, ".env.") {
+	case "example", "sample", "template", "dist":
+		return false
+	}
+	return true
+}
+
+// summarize lists up to max items, followed by the number left out
+func summarize(items []string, max int) string {
+	if len(items) <= max {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:max], ", "), len(items)-max)
+}
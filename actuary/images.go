@@ -11,7 +11,14 @@ package actuary
 
 import (
 	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"golang.org/x/net/context"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 func CheckContainerUser(t Target) (res Result) {
@@ -47,3 +54,308 @@ func CheckContentTrust(t Target) (res Result) {
 	}
 	return
 }
+
+// imageConfig is the runtime configuration an image ends up with
+type imageConfig struct {
+	User        string
+	Healthcheck bool
+	Env         []string
+}
+
+// buildStep is a single build instruction, recovered from image history
+type buildStep struct {
+	Instruction string
+	Args        string
+	// BuildArgs holds the build arguments recorded alongside a RUN step
+	BuildArgs map[string]string
+	Source    string
+}
+
+// imageRule is a best practice evaluated against the configuration and build
+// steps of every image in use. Check returns one description per violation.
+type imageRule struct {
+	ID    string
+	Name  string
+	Check func(cfg imageConfig, steps []buildStep) []string
+}
+
+// imageCache holds the inspect output and history of the images in use, which
+// every image rule reads, so that each image is fetched only once
+type imageCache struct {
+	sync.Mutex
+	images map[string]*imageDetails
+}
+
+type imageDetails struct {
+	inspect types.ImageInspect
+	history []image.HistoryResponseItem
+	err     error
+}
+
+var instructions = []string{"ADD", "ARG", "CMD", "COPY", "ENTRYPOINT", "ENV", "EXPOSE",
+	"FROM", "HEALTHCHECK", "LABEL", "MAINTAINER", "ONBUILD", "RUN", "SHELL", "STOPSIGNAL",
+	"USER", "VOLUME", "WORKDIR"}
+
+var upgradeRegexp = regexp.MustCompile(`\b(apt-get|apt|aptitude)\s+(-\S+\s+)*(dist-upgrade|full-upgrade|upgrade)\b|\b(yum|dnf|microdnf)\s+(-\S+\s+)*(upgrade|update)\s*($|&&|;|\|)|\bapk\s+(-\S+\s+)*upgrade\b`)
+
+var secretRegexp = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api_?key|access_?key|private_?key|credential)`)
+
+var imageRules = []imageRule{
+	{
+		ID:   "image_user",
+		Name: "4.1 Create a user for the container image",
+		Check: func(cfg imageConfig, steps []buildStep) []string {
+			if isRootUser(cfg.User) {
+				return []string{"image runs as root"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:   "image_healthcheck",
+		Name: "4.6 Add HEALTHCHECK instruction to the container image",
+		Check: func(cfg imageConfig, steps []buildStep) []string {
+			if !cfg.Healthcheck {
+				return []string{"no HEALTHCHECK defined"}
+			}
+			return nil
+		},
+	},
+	{
+		ID:   "image_upgrade",
+		Name: "4.7 Do not upgrade all packages in image layers",
+		Check: func(cfg imageConfig, steps []buildStep) (findings []string) {
+			for _, step := range steps {
+				if step.Instruction == "RUN" && upgradeRegexp.MatchString(step.Args) {
+					findings = append(findings, truncate(step.Source))
+				}
+			}
+			return
+		},
+	},
+	{
+		ID:   "image_add_url",
+		Name: "4.9 Use COPY instead of ADD for remote files",
+		Check: func(cfg imageConfig, steps []buildStep) (findings []string) {
+			for _, step := range steps {
+				if step.Instruction == "ADD" && strings.Contains(step.Args, "://") {
+					findings = append(findings, truncate(step.Source))
+				}
+			}
+			return
+		},
+	},
+	{
+		ID:   "image_secret_env",
+		Name: "4.10 Do not store secrets in image environment variables",
+		Check: func(cfg imageConfig, steps []buildStep) (findings []string) {
+			for _, env := range cfg.Env {
+				kv := strings.SplitN(env, "=", 2)
+				if len(kv) != 2 || !isSecretName(kv[0]) || kv[1] == "" {
+					continue
+				}
+				finding := fmt.Sprintf("ENV %s", kv[0])
+				for _, step := range steps {
+					if step.Instruction == "ENV" && strings.Contains(step.Args, kv[0]) {
+						// Mask the assignment only, as a short value may
+						// appear elsewhere in the step
+						source := strings.Replace(step.Source, env, kv[0]+"=****", -1)
+						finding = fmt.Sprintf("ENV %s set by %s", kv[0], truncate(source))
+					}
+				}
+				findings = append(findings, finding)
+			}
+			return
+		},
+	},
+	{
+		ID:   "image_build_args",
+		Name: "4.10 Do not pass secrets as build arguments",
+		Check: func(cfg imageConfig, steps []buildStep) (findings []string) {
+			for _, step := range steps {
+				for key, val := range step.BuildArgs {
+					if isSecretName(key) && val != "" {
+						findings = append(findings, fmt.Sprintf("build arg %s recorded in %s", key,
+							truncate(step.Source)))
+					}
+				}
+			}
+			return
+		},
+	},
+}
+
+func init() {
+	for _, rule := range imageRules {
+		checklist[rule.ID] = rule.Audit
+	}
+}
+
+// Audit evaluates the rule against every image used by a container on the target
+func (r imageRule) Audit(t Target) (res Result) {
+	var findings []string
+	res.Name = r.Name
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, img := range getImagesInUse(t) {
+		details, err := getImageDetails(t, img.ID)
+		if err != nil {
+			continue
+		}
+		for _, finding := range r.Check(getImageConfig(details.inspect), getBuildSteps(details.history)) {
+			findings = append(findings, fmt.Sprintf("%s: %s", img.Ref, finding))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail(strings.Join(findings, "; "))
+	}
+	return
+}
+
+// getImageDetails returns the inspect output and history of an image, fetching
+// them on first use
+func getImageDetails(t Target, id string) (details *imageDetails, err error) {
+	fetch := func() (details *imageDetails) {
+		details = new(imageDetails)
+		details.inspect, _, details.err = t.Client.ImageInspectWithRaw(context.TODO(), id)
+		if details.err == nil {
+			details.history, details.err = t.Client.ImageHistory(context.TODO(), id)
+		}
+		return
+	}
+	if t.images == nil {
+		details = fetch()
+		return details, details.err
+	}
+	t.images.Lock()
+	defer t.images.Unlock()
+	if t.images.images == nil {
+		t.images.images = make(map[string]*imageDetails)
+	}
+	details, ok := t.images.images[id]
+	if !ok {
+		details = fetch()
+		t.images.images[id] = details
+	}
+	return details, details.err
+}
+
+type usedImage struct {
+	ID  string
+	Ref string
+	// Containers lists the IDs of the containers using the image
+	Containers []string
+}
+
+// Returns each distinct image used by the target's containers, along with
+// the reference the first container using it was created from
+func getImagesInUse(t Target) (images []usedImage) {
+	seen := make(map[string]int)
+	for _, container := range t.Containers {
+		if container.Info.ContainerJSONBase == nil || container.Info.Image == "" {
+			continue
+		}
+		id := container.Info.Image
+		if i, ok := seen[id]; ok {
+			images[i].Containers = append(images[i].Containers, container.ID)
+			continue
+		}
+		seen[id] = len(images)
+		ref := id
+		if container.Info.Config != nil && container.Info.Config.Image != "" {
+			ref = container.Info.Config.Image
+		}
+		images = append(images, usedImage{ID: id, Ref: ref, Containers: []string{container.ID}})
+	}
+	return
+}
+
+func getImageConfig(inspect types.ImageInspect) (cfg imageConfig) {
+	if inspect.Config == nil {
+		return
+	}
+	cfg.User = inspect.Config.User
+	cfg.Env = inspect.Config.Env
+	hc := inspect.Config.Healthcheck
+	cfg.Healthcheck = hc != nil && len(hc.Test) != 0 && hc.Test[0] != "NONE"
+	return
+}
+
+// Converts image history, newest entry first, into build steps in build order
+func getBuildSteps(history []image.HistoryResponseItem) (steps []buildStep) {
+	for i := len(history) - 1; i >= 0; i-- {
+		steps = append(steps, parseHistoryEntry(history[i].CreatedBy))
+	}
+	return
+}
+
+// parseHistoryEntry recovers the instruction behind a history entry. The classic
+// builder records "/bin/sh -c #(nop) <INSTRUCTION> ..." for metadata steps and
+// "[|N ARG=val...] /bin/sh -c <cmd>" for RUN steps; BuildKit records the
+// instruction itself followed by "# buildkit".
+func parseHistoryEntry(createdBy string) (step buildStep) {
+	step.Source = createdBy
+	s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(createdBy), "# buildkit"))
+	if strings.HasPrefix(s, "/bin/sh -c #(nop)") {
+		s = strings.TrimSpace(strings.TrimPrefix(s, "/bin/sh -c #(nop)"))
+	} else {
+		s = strings.TrimPrefix(s, "RUN ")
+	}
+	if strings.HasPrefix(s, "|") {
+		fields := strings.Fields(s)
+		n, err := strconv.Atoi(strings.TrimPrefix(fields[0], "|"))
+		if err == nil && n < len(fields) {
+			step.BuildArgs = make(map[string]string)
+			for _, arg := range fields[1 : n+1] {
+				kv := strings.SplitN(arg, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				step.BuildArgs[kv[0]] = kv[1]
+				// Keep secret values out of the findings that quote this step
+				if isSecretName(kv[0]) {
+					step.Source = strings.Replace(step.Source, arg, kv[0]+"=****", -1)
+				}
+			}
+			s = strings.Join(fields[n+1:], " ")
+		}
+	}
+	if strings.HasPrefix(s, "/bin/sh -c ") {
+		step.Instruction = "RUN"
+		step.Args = strings.TrimPrefix(s, "/bin/sh -c ")
+		return
+	}
+	parts := strings.SplitN(s, " ", 2)
+	if stringInSlice(strings.ToUpper(parts[0]), instructions) {
+		step.Instruction = strings.ToUpper(parts[0])
+		if len(parts) > 1 {
+			step.Args = strings.TrimSpace(parts[1])
+		}
+	}
+	return
+}
+
+func isRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name == "" || name == "root" || name == "0"
+}
+
+// Secret-looking names, excluding those pointing at a file holding the secret
+func isSecretName(name string) bool {
+	upper := strings.ToUpper(name)
+	if strings.HasSuffix(upper, "_FILE") || strings.HasSuffix(upper, "_PATH") {
+		return false
+	}
+	return secretRegexp.MatchString(name)
+}
+
+func truncate(s string) string {
+	if len(s) > 120 {
+		return s[:117] + "..."
+	}
+	return s
+}
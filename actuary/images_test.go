@@ -1,8 +1,15 @@
 package actuary
 
 import (
+	"encoding/json"
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -57,3 +64,104 @@ func TestCheckContentTrustFail(t *testing.T) {
 	res := CheckContentTrust(*testTarget)
 	assert.Equal(t, "WARN", res.Status, "Content trust for Docker disabled, should not have passed.")
 }
+
+func TestParseHistoryEntry(t *testing.T) {
+	step := parseHistoryEntry(`/bin/sh -c #(nop)  HEALTHCHECK &{["CMD-SHELL" "curl -f localhost"]}`)
+	assert.Equal(t, "HEALTHCHECK", step.Instruction, "Classic builder metadata step.")
+	step = parseHistoryEntry(`|2 TOKEN=abc VERSION=1 /bin/sh -c apt-get update && apt-get upgrade -y`)
+	assert.Equal(t, "RUN", step.Instruction, "Classic builder RUN step with build args.")
+	assert.Equal(t, "abc", step.BuildArgs["TOKEN"], "Build args recorded in history.")
+	assert.Equal(t, "apt-get update && apt-get upgrade -y", step.Args, "RUN command.")
+	step = parseHistoryEntry(`ADD https://example.com/app.tar.gz /app # buildkit`)
+	assert.Equal(t, "ADD", step.Instruction, "BuildKit ADD step.")
+	assert.Equal(t, "https://example.com/app.tar.gz /app", step.Args, "BuildKit ADD args.")
+}
+
+func TestImageSecretEnvMasking(t *testing.T) {
+	cfg := imageConfig{Env: []string{"API_TOKEN=1"}}
+	steps := []buildStep{parseHistoryEntry("/bin/sh -c #(nop)  ENV API_TOKEN=1 RETRIES=10")}
+	for _, rule := range imageRules {
+		if rule.ID != "image_secret_env" {
+			continue
+		}
+		findings := rule.Check(cfg, steps)
+		assert.Equal(t, []string{"ENV API_TOKEN set by /bin/sh -c #(nop)  ENV API_TOKEN=**** RETRIES=10"}, findings,
+			"Only the secret assignment should be masked.")
+	}
+}
+
+func imageTestTarget(t *testing.T, inspect types.ImageInspect, history []image.HistoryResponseItem) (*Target, *httptest.Server) {
+	testTarget := imageFSTestTarget(t, "sha256:abc")
+	inspectJSON, err := json.Marshal(inspect)
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		t.Errorf("Could not convert image to json.")
+	}
+	p1 := callPairing{"/images/sha256:abc/json", inspectJSON}
+	p2 := callPairing{"/images/sha256:abc/history", historyJSON}
+	return testTarget, testTarget.testServer(t, p1, p2)
+}
+
+func TestImageRulesSuccess(t *testing.T) {
+	inspect := types.ImageInspect{Config: &container.Config{
+		User:        "app",
+		Env:         []string{"PATH=/usr/bin", "DB_PASSWORD_FILE=/run/secrets/db"},
+		Healthcheck: &container.HealthConfig{Test: []string{"CMD", "true"}},
+	}}
+	history := []image.HistoryResponseItem{
+		{CreatedBy: "/bin/sh -c #(nop)  USER app"},
+		{CreatedBy: "/bin/sh -c apt-get update && apt-get install -y curl"},
+		{CreatedBy: "/bin/sh -c #(nop) COPY file:abc in /app"},
+	}
+	testTarget, ts := imageTestTarget(t, inspect, history)
+	defer ts.Close()
+	for _, rule := range imageRules {
+		res := rule.Audit(*testTarget)
+		assert.Equal(t, "PASS", res.Status, rule.ID+" should pass.")
+	}
+}
+
+func TestImageRulesFail(t *testing.T) {
+	inspect := types.ImageInspect{Config: &container.Config{
+		User: "root",
+		Env:  []string{"API_KEY=hunter2"},
+	}}
+	history := []image.HistoryResponseItem{
+		{CreatedBy: "/bin/sh -c #(nop)  ENV API_KEY=hunter2"},
+		{CreatedBy: "|1 GITHUB_TOKEN=ghp_123 /bin/sh -c yum -y update && yum clean all"},
+		{CreatedBy: "ADD https://example.com/app.tar.gz /app # buildkit"},
+	}
+	testTarget, ts := imageTestTarget(t, inspect, history)
+	defer ts.Close()
+	for _, rule := range imageRules {
+		res := rule.Audit(*testTarget)
+		assert.Equal(t, "WARN", res.Status, rule.ID+" should not pass.")
+		assert.NotContains(t, res.Output, "hunter2", "Secret values should be masked.")
+		assert.NotContains(t, res.Output, "ghp_123", "Secret values should be masked.")
+	}
+}
+
+func TestImageRulesFetchImageOnce(t *testing.T) {
+	testTarget := imageFSTestTarget(t, "sha256:abc")
+	calls := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1.31/images/sha256:abc/history" {
+			w.Write([]byte("[]"))
+		} else {
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer ts.Close()
+	var err error
+	testTarget.Client, err = client.NewClient(ts.URL, api.DefaultVersion, nil, nil)
+	if err != nil {
+		t.Errorf("Could not manipulate test target client.")
+	}
+	for _, rule := range imageRules {
+		rule.Audit(*testTarget)
+	}
+	assert.Equal(t, 1, calls["/v1.31/images/sha256:abc/json"], "Image should be inspected once for all the rules.")
+	assert.Equal(t, 1, calls["/v1.31/images/sha256:abc/history"], "Image history should be fetched once for all the rules.")
+}
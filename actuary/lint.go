@@ -0,0 +1,319 @@
+/*
+Package checks - 4 Container Images and Build File (Dockerfile linting)
+The image rules can be applied to a Dockerfile before it is ever built. The
+Dockerfile is reduced to the configuration its final stage produces and the list
+of build steps of every stage, which the rules evaluate as they would for an image.
+*/
+package actuary
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Only the Dockerfile tells which base image a build starts from, so this rule
+// is not part of the checks run against images in use. FROM steps are recorded
+// only for stages that do not build on an earlier stage.
+var pinnedBaseRule = imageRule{
+	ID:   "dockerfile_pinned_base",
+	Name: "Pin base images by digest",
+	Check: func(cfg imageConfig, steps []buildStep) (findings []string) {
+		for _, step := range steps {
+			if step.Instruction != "FROM" || strings.ToLower(step.Args) == "scratch" {
+				continue
+			}
+			if !strings.Contains(step.Args, "@sha256:") {
+				findings = append(findings, truncate(step.Source))
+			}
+		}
+		return
+	},
+}
+
+var (
+	directiveRegexp   = regexp.MustCompile(`^#\s*([a-zA-Z]+)\s*=\s*(\S+)\s*$`)
+	instructionRegexp = regexp.MustCompile(`^(\S+)\s*(.*)$`)
+)
+
+// dockerfileLine is an instruction with its continuation lines joined
+type dockerfileLine struct {
+	Num  int
+	Text string
+}
+
+// dockerfileStage holds the state of a build stage while its instructions are read
+type dockerfileStage struct {
+	Name string
+	Cfg  imageConfig
+	Env  map[string]string
+	Args map[string]string
+}
+
+func (s *dockerfileStage) lookup(name string) (string, bool) {
+	if val, ok := s.Env[name]; ok {
+		return val, true
+	}
+	val, ok := s.Args[name]
+	return val, ok
+}
+
+func (s *dockerfileStage) setEnv(key, val string) {
+	s.Env[key] = val
+	for i, env := range s.Cfg.Env {
+		if strings.SplitN(env, "=", 2)[0] == key {
+			s.Cfg.Env[i] = key + "=" + val
+			return
+		}
+	}
+	s.Cfg.Env = append(s.Cfg.Env, key+"="+val)
+}
+
+// LintDockerfiles evaluates the image rules against each Dockerfile. Build
+// arguments override the defaults declared by ARG instructions.
+func LintDockerfiles(paths []string, buildArgs map[string]string) (results []Result, err error) {
+	type lintedFile struct {
+		path  string
+		cfg   imageConfig
+		steps []buildStep
+	}
+	var files []lintedFile
+	for _, path := range paths {
+		cfg, steps, err := parseDockerfile(path, buildArgs)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, lintedFile{path, cfg, steps})
+	}
+	rules := append([]imageRule{pinnedBaseRule}, imageRules...)
+	for _, rule := range rules {
+		var findings []string
+		res := Result{Name: rule.Name}
+		for _, file := range files {
+			for _, finding := range rule.Check(file.cfg, file.steps) {
+				findings = append(findings, fmt.Sprintf("%s: %s", file.path, finding))
+			}
+		}
+		if len(findings) == 0 {
+			res.Pass()
+		} else {
+			res.Fail(strings.Join(findings, "; "))
+		}
+		results = append(results, res)
+	}
+	return
+}
+
+// parseDockerfile returns the configuration of the image built by the final
+// stage and the build steps of all stages
+func parseDockerfile(path string, buildArgs map[string]string) (cfg imageConfig, steps []buildStep, err error) {
+	lines, err := readDockerfile(path)
+	if err != nil {
+		return
+	}
+	globalArgs := make(map[string]string)
+	var stages []*dockerfileStage
+	var stage *dockerfileStage
+	for _, line := range lines {
+		match := instructionRegexp.FindStringSubmatch(line.Text)
+		instruction, args := strings.ToUpper(match[1]), match[2]
+		if !stringInSlice(instruction, instructions) {
+			err = fmt.Errorf("%s:%d: unknown instruction: %s", path, line.Num, match[1])
+			return
+		}
+		step := buildStep{
+			Instruction: instruction,
+			Source:      fmt.Sprintf("line %d: %s", line.Num, line.Text),
+		}
+		if stage == nil && instruction != "FROM" && instruction != "ARG" {
+			err = fmt.Errorf("%s:%d: %s before the first FROM", path, line.Num, instruction)
+			return
+		}
+		switch instruction {
+		case "FROM":
+			var image, name string
+			words := strings.Fields(expandVars(args, func(key string) (string, bool) {
+				val, ok := globalArgs[key]
+				return val, ok
+			}))
+			for i := 0; i < len(words); i++ {
+				switch {
+				case strings.HasPrefix(words[i], "--"):
+				case strings.EqualFold(words[i], "AS") && i+1 < len(words):
+					name = strings.ToLower(words[i+1])
+					i++
+				case image == "":
+					image = words[i]
+				}
+			}
+			if image == "" {
+				err = fmt.Errorf("%s:%d: FROM requires an image", path, line.Num)
+				return
+			}
+			stage = &dockerfileStage{
+				Name: name,
+				Env:  make(map[string]string),
+				Args: make(map[string]string),
+			}
+			var parent *dockerfileStage
+			for _, s := range stages {
+				if s.Name != "" && s.Name == strings.ToLower(image) {
+					parent = s
+				}
+			}
+			if parent != nil {
+				stage.Cfg = parent.Cfg
+				stage.Cfg.Env = append([]string(nil), parent.Cfg.Env...)
+				for k, v := range parent.Env {
+					stage.Env[k] = v
+				}
+			} else {
+				step.Args = image
+				steps = append(steps, step)
+			}
+			stages = append(stages, stage)
+			continue
+		case "ARG":
+			for _, word := range splitCommandLine(args) {
+				kv := strings.SplitN(word, "=", 2)
+				key, val := kv[0], ""
+				if len(kv) == 2 {
+					if stage == nil {
+						val = kv[1]
+					} else {
+						val = expandVars(kv[1], stage.lookup)
+					}
+					if isSecretName(key) && kv[1] != "" {
+						step.Source = strings.Replace(step.Source, kv[1], "****", -1)
+					}
+				} else if stage != nil {
+					val = globalArgs[key]
+				}
+				if v, ok := buildArgs[key]; ok {
+					val = v
+				}
+				if stage == nil {
+					globalArgs[key] = val
+					continue
+				}
+				stage.Args[key] = val
+				// The value is usually only known at build time; either way it is
+				// recorded with every RUN step that follows
+				if step.BuildArgs == nil {
+					step.BuildArgs = make(map[string]string)
+				}
+				if val == "" {
+					val = "(set at build time)"
+				}
+				step.BuildArgs[key] = val
+			}
+			if stage == nil {
+				continue
+			}
+		case "ENV":
+			words := splitCommandLine(expandVars(args, stage.lookup))
+			if len(words) != 0 && !strings.Contains(words[0], "=") {
+				// Legacy form: ENV <key> <value>
+				parts := strings.SplitN(strings.TrimSpace(args), " ", 2)
+				if len(parts) == 2 {
+					stage.setEnv(parts[0], strings.TrimSpace(expandVars(parts[1], stage.lookup)))
+				}
+			} else {
+				for _, word := range words {
+					if kv := strings.SplitN(word, "=", 2); len(kv) == 2 {
+						stage.setEnv(kv[0], kv[1])
+					}
+				}
+			}
+			step.Args = args
+		case "USER":
+			stage.Cfg.User = expandVars(args, stage.lookup)
+			step.Args = stage.Cfg.User
+		case "HEALTHCHECK":
+			stage.Cfg.Healthcheck = strings.ToUpper(strings.TrimSpace(args)) != "NONE"
+			step.Args = args
+		case "ADD", "COPY", "WORKDIR", "LABEL", "EXPOSE", "VOLUME", "STOPSIGNAL":
+			step.Args = expandVars(args, stage.lookup)
+		default:
+			step.Args = args
+		}
+		steps = append(steps, step)
+	}
+	if stage == nil {
+		err = fmt.Errorf("%s: no FROM instruction found", path)
+		return
+	}
+	cfg = stage.Cfg
+	return
+}
+
+// readDockerfile returns the instructions of a Dockerfile, honouring the escape
+// parser directive and dropping comments
+func readDockerfile(path string) (lines []dockerfileLine, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	escape := `\`
+	directives := true
+	var current dockerfileLine
+	scanner := bufio.NewScanner(file)
+	num := 0
+	for scanner.Scan() {
+		num++
+		text := strings.TrimSpace(scanner.Text())
+		if directives {
+			if match := directiveRegexp.FindStringSubmatch(text); match != nil {
+				if strings.ToLower(match[1]) == "escape" {
+					escape = match[2]
+				}
+				continue
+			}
+			directives = false
+		}
+		if strings.HasPrefix(text, "#") || (text == "" && current.Text == "") {
+			continue
+		}
+		if current.Text == "" {
+			current.Num = num
+		}
+		if strings.HasSuffix(text, escape) {
+			current.Text += strings.TrimSuffix(text, escape) + " "
+			continue
+		}
+		current.Text += text
+		lines = append(lines, dockerfileLine{current.Num, strings.TrimSpace(current.Text)})
+		current = dockerfileLine{}
+	}
+	if strings.TrimSpace(current.Text) != "" {
+		lines = append(lines, dockerfileLine{current.Num, strings.TrimSpace(current.Text)})
+	}
+	err = scanner.Err()
+	return
+}
+
+// expandVars substitutes $VAR, ${VAR}, ${VAR:-default} and ${VAR:+alternative}
+func expandVars(s string, lookup func(string) (string, bool)) string {
+	return os.Expand(s, func(key string) string {
+		name, op, word := key, "", ""
+		if i := strings.Index(key, ":"); i > 0 && i+1 < len(key) {
+			name, op, word = key[:i], key[i:i+2], key[i+2:]
+		}
+		val, ok := lookup(name)
+		switch op {
+		case ":-":
+			if !ok || val == "" {
+				return word
+			}
+		case ":+":
+			if ok && val != "" {
+				return word
+			}
+			return ""
+		}
+		return val
+	})
+}
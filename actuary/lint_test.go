@@ -0,0 +1,114 @@
+package actuary
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDockerfile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "actuary")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "Dockerfile")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Could not write Dockerfile: %v", err)
+	}
+	return path
+}
+
+func TestParseDockerfile(t *testing.T) {
+	path := writeDockerfile(t, `# escape=\
+ARG GO_VERSION=1.9
+FROM golang:${GO_VERSION} AS build
+ENV APP_HOME=/src
+WORKDIR $APP_HOME
+RUN go build \
+    # comment inside a continuation
+    -o /app .
+
+FROM build AS test
+USER ${RUNAS:-nobody}
+
+FROM alpine@sha256:0123
+COPY --from=build /app /app
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+	cfg, steps, err := parseDockerfile(path, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", cfg.User, "Final stage does not inherit from the test stage")
+	var froms []string
+	for _, step := range steps {
+		if step.Instruction == "FROM" {
+			froms = append(froms, step.Args)
+		}
+		if step.Instruction == "WORKDIR" {
+			assert.Equal(t, "/src", step.Args)
+		}
+		if step.Instruction == "RUN" {
+			assert.Equal(t, "line 6: RUN go build  -o /app .", step.Source)
+		}
+	}
+	assert.Equal(t, []string{"golang:1.9", "alpine@sha256:0123"}, froms)
+
+	_, steps, err = parseDockerfile(path, map[string]string{"GO_VERSION": "1.10"})
+	assert.Nil(t, err)
+	assert.Equal(t, "golang:1.10", steps[0].Args, "Build args override ARG defaults")
+}
+
+func TestParseDockerfileInheritsStage(t *testing.T) {
+	path := writeDockerfile(t, `FROM debian AS base
+ENV API_TOKEN=abc
+USER app
+FROM base
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+	cfg, _, err := parseDockerfile(path, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "app", cfg.User)
+	assert.Equal(t, []string{"API_TOKEN=abc"}, cfg.Env)
+}
+
+func TestParseDockerfileInvalid(t *testing.T) {
+	path := writeDockerfile(t, "RUN true\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	_, _, err := parseDockerfile(path, nil)
+	assert.NotNil(t, err, "RUN before FROM should be rejected")
+}
+
+func TestLintDockerfilesSuccess(t *testing.T) {
+	path := writeDockerfile(t, `FROM golang@sha256:0123 AS build
+RUN go build -o /app .
+FROM scratch
+COPY --from=build /app /app
+USER 1000
+HEALTHCHECK CMD ["/app", "-health"]
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+	results, err := LintDockerfiles([]string{path}, nil)
+	assert.Nil(t, err)
+	for _, res := range results {
+		assert.Equal(t, "PASS", res.Status, res.Name+": "+res.Output)
+	}
+}
+
+func TestLintDockerfilesFail(t *testing.T) {
+	path := writeDockerfile(t, `FROM debian:stretch
+ARG NPM_TOKEN=s3cr3t
+ENV DB_PASSWORD=hunter2
+ADD https://example.com/app.tar.gz /
+RUN apt-get update && apt-get -y upgrade
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+	results, err := LintDockerfiles([]string{path}, nil)
+	assert.Nil(t, err)
+	for _, res := range results {
+		assert.Equal(t, "WARN", res.Status, res.Name+" should have failed")
+		assert.False(t, strings.Contains(res.Output, "s3cr3t") || strings.Contains(res.Output, "hunter2"),
+			"Secret values should not be reported")
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Package checks - 5 Container Runtime (logging)
+The daemon-wide log driver is only a default: each container may pick its own
+driver and options when it is started. These checks read the logging
+configuration of every container, and the size of the logs the json-file
+driver leaves on the host.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/go-units"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func CheckContainerLogging(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that container logs are kept and rotated"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		logConfig := c.Info.HostConfig.LogConfig
+		switch getLogDriver(t, c) {
+		case "none":
+			findings = append(findings, fmt.Sprintf("%s: logging disabled", c.ID))
+		case "json-file":
+			// The daemon's default log options are merged in when the
+			// container is created
+			var missing []string
+			for _, opt := range []string{"max-size", "max-file"} {
+				if logConfig.Config[opt] == "" {
+					missing = append(missing, opt)
+				}
+			}
+			if len(missing) != 0 {
+				findings = append(findings, fmt.Sprintf("%s: json-file without %s", c.ID, strings.Join(missing, ", ")))
+			}
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers with unbounded or disabled logs: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckContainerLogDriver(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that containers use an approved log driver"
+	allowed := t.Policy.Logging.AllowedDrivers
+	if len(allowed) == 0 {
+		res.Skip("No approved log drivers configured")
+		return
+	}
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		if driver := getLogDriver(t, c); !stringInSlice(driver, allowed) {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, driver))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers using log drivers not approved: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckJSONLogSize(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that json-file container logs are not oversized"
+	maxSize, err := units.RAMInBytes(t.Policy.Logging.maxLogSize())
+	if err != nil {
+		res.Skip(fmt.Sprintf("Invalid maximum log size: %v", err))
+		return
+	}
+	if t.Info.DockerRootDir == "" {
+		res.Skip("Docker root directory unknown")
+		return
+	}
+	dir := filepath.Join(t.BaseDir, t.Info.DockerRootDir, "containers")
+	if _, err := os.Stat(dir); err != nil {
+		res.Skip(fmt.Sprintf("Cannot read %s", dir))
+		return
+	}
+	// Logs are kept as containers/<id>/<id>-json.log. Rotated files get a
+	// numbered suffix and are bounded by max-size already.
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", "*-json.log"))
+	for _, fpath := range matches {
+		info, err := os.Stat(fpath)
+		if err != nil || info.Size() <= maxSize {
+			continue
+		}
+		id := filepath.Base(filepath.Dir(fpath))
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		findings = append(findings, fmt.Sprintf("%s (%s)", id, units.BytesSize(float64(info.Size()))))
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Container logs larger than %s: %s",
+			units.BytesSize(float64(maxSize)), summarize(findings, 10))
+		res.Fail(output)
+	}
+	return
+}
+
+// getLogDriver returns the log driver of a container, falling back to the
+// daemon's default for containers inspected without one
+func getLogDriver(t Target, c Container) string {
+	if driver := c.Info.HostConfig.LogConfig.Type; driver != "" {
+		return driver
+	}
+	if t.Info.LoggingDriver != "" {
+		return t.Info.LoggingDriver
+	}
+	return "json-file"
+}
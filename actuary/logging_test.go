@@ -0,0 +1,113 @@
+package actuary
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckContainerLoggingSuccess(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{LogConfig: container.LogConfig{
+			Type:   "json-file",
+			Config: map[string]string{"max-size": "10m", "max-file": "3"},
+		}}},
+	})
+	res := CheckContainerLogging(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "json-file logs are rotated, should have passed.")
+
+	testTarget = newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{LogConfig: container.LogConfig{Type: "syslog"}}},
+	})
+	res = CheckContainerLogging(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "syslog logging, should have passed.")
+}
+
+func TestCheckContainerLoggingFail(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{LogConfig: container.LogConfig{Type: "none"}}},
+	})
+	res := CheckContainerLogging(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Logging disabled, should not have passed.")
+	assert.Equal(t, "Containers with unbounded or disabled logs: Container_id1: logging disabled", res.Output)
+
+	testTarget = newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{LogConfig: container.LogConfig{
+			Type:   "json-file",
+			Config: map[string]string{"max-file": "3"},
+		}}},
+	})
+	res = CheckContainerLogging(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "json-file logs without max-size, should not have passed.")
+	assert.Equal(t, "Containers with unbounded or disabled logs: Container_id1: json-file without max-size", res.Output)
+}
+
+func TestCheckContainerLoggingDaemonDefault(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{LogConfig: container.LogConfig{}}},
+	})
+	testTarget.Info.LoggingDriver = "none"
+	res := CheckContainerLogging(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Daemon default disables logging, should not have passed.")
+}
+
+func TestCheckContainerLogDriver(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{LogConfig: container.LogConfig{Type: "json-file"}}},
+	})
+	res := CheckContainerLogDriver(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No approved log drivers, should skip.")
+
+	testTarget.Policy.Logging.AllowedDrivers = []string{"syslog", "journald"}
+	res = CheckContainerLogDriver(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "json-file not approved, should not have passed.")
+	assert.Equal(t, "Containers using log drivers not approved: Container_id1: json-file", res.Output)
+
+	testTarget.Policy.Logging.AllowedDrivers = []string{"json-file"}
+	res = CheckContainerLogDriver(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "json-file approved, should have passed.")
+}
+
+func TestCheckJSONLogSize(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testTarget.BaseDir = dir
+	testTarget.Info.DockerRootDir = "/var/lib/docker"
+	testTarget.Policy.Logging.MaxLogSize = "1k"
+	logs := map[string]int{
+		"0123456789abcdef": 2048,
+		"fedcba9876543210": 512,
+	}
+	for id, size := range logs {
+		fpath := filepath.Join(dir, "var/lib/docker/containers", id, id+"-json.log")
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fpath, []byte(strings.Repeat("x", size)), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := CheckJSONLogSize(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Oversized log, should not have passed.")
+	assert.Equal(t, "Container logs larger than 1KiB: 0123456789ab (2KiB)", res.Output)
+
+	testTarget.Policy.Logging.MaxLogSize = "4k"
+	res = CheckJSONLogSize(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Logs below the limit, should have passed.")
+
+	testTarget.Info.DockerRootDir = ""
+	res = CheckJSONLogSize(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "Unknown root directory, should skip.")
+}
@@ -0,0 +1,187 @@
+/*
+Package checks - 5 Container Runtime (Linux security modules)
+Containers are confined by the AppArmor profile or SELinux label the daemon
+applies to them, unless security options turn the confinement off. The labels
+reported by inspect are checked, along with the options that weaken them.
+*/
+package actuary
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// apparmorProfilesFile lists the AppArmor profiles loaded in the kernel, one
+// "name (mode)" per line
+const apparmorProfilesFile = "/sys/kernel/security/apparmor/profiles"
+
+// SELinux types that run processes without confinement
+var unconfinedSELinuxTypes = []string{"spc_t", "unconfined_t"}
+
+func CheckAppArmor(t Target) (res Result) {
+	var findings []string
+	res.Name = "5.1 Verify AppArmor Profile, if applicable"
+	if !lsmEnabled(t, "apparmor") {
+		res.Skip("AppArmor is not enabled on the daemon")
+		return
+	}
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	// Loaded profiles can only be verified where securityfs is readable
+	loaded, err := getAppArmorProfiles(filepath.Join(t.BaseDir, apparmorProfilesFile))
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil {
+			continue
+		}
+		profile := c.Info.AppArmorProfile
+		if c.Info.HostConfig != nil {
+			if opt, ok := getSecurityOpt(c.Info.HostConfig.SecurityOpt, "apparmor"); ok {
+				profile = opt
+			}
+		}
+		var desc string
+		switch mode, isLoaded := loaded[profile]; {
+		case profile == "":
+			desc = "no profile"
+		case profile == "unconfined":
+			desc = "unconfined"
+		case err == nil && !isLoaded:
+			desc = fmt.Sprintf("profile %s not loaded", profile)
+		case err == nil && mode != "enforce":
+			desc = fmt.Sprintf("profile %s in %s mode", profile, mode)
+		}
+		if desc != "" {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, desc))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers without an enforced AppArmor profile: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckSELinux(t Target) (res Result) {
+	var findings []string
+	res.Name = "5.2 Verify SELinux security options, if applicable"
+	if !lsmEnabled(t, "selinux") {
+		res.Skip("SELinux is not enabled on the daemon")
+		return
+	}
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil {
+			continue
+		}
+		var opts []string
+		if c.Info.HostConfig != nil {
+			opts = getLabelOpts(c.Info.HostConfig.SecurityOpt)
+		}
+		var desc string
+		switch {
+		case stringInSlice("disable", opts):
+			desc = "labeling disabled"
+		case c.Info.ProcessLabel == "" && c.Info.MountLabel == "":
+			desc = "no SELinux label"
+		default:
+			for _, opt := range opts {
+				if kv := strings.SplitN(opt, ":", 2); len(kv) == 2 && kv[0] == "type" &&
+					stringInSlice(kv[1], unconfinedSELinuxTypes) {
+					desc = "unconfined type " + kv[1]
+				}
+			}
+			if labelType := getSELinuxType(c.Info.ProcessLabel); stringInSlice(labelType, unconfinedSELinuxTypes) {
+				desc = "unconfined type " + labelType
+			}
+		}
+		if desc != "" {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, desc))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers without an enforced SELinux label: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+// getSecurityOpt returns the value of a "name=value" security option. Older
+// clients separate the name with a colon.
+func getSecurityOpt(opts []string, name string) (string, bool) {
+	for _, opt := range opts {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			kv = strings.SplitN(opt, ":", 2)
+		}
+		if len(kv) == 2 && kv[0] == name {
+			return kv[1], true
+		}
+	}
+	return "", false
+}
+
+// getLabelOpts returns the SELinux label options, e.g. "disable" or "type:spc_t"
+func getLabelOpts(opts []string) (labels []string) {
+	for _, opt := range opts {
+		for _, prefix := range []string{"label=", "label:"} {
+			if strings.HasPrefix(opt, prefix) {
+				labels = append(labels, strings.TrimPrefix(opt, prefix))
+				break
+			}
+		}
+	}
+	return
+}
+
+// getSELinuxType returns the type of a "user:role:type:level" label
+func getSELinuxType(label string) string {
+	parts := strings.SplitN(label, ":", 4)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+// getAppArmorProfiles maps the loaded AppArmor profiles to their mode
+func getAppArmorProfiles(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	profiles := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.LastIndex(line, " ("); i > 0 && strings.HasSuffix(line, ")") {
+			profiles[line[:i]] = line[i+2 : len(line)-1]
+		}
+	}
+	return profiles, scanner.Err()
+}
+
+// lsmEnabled tells whether the daemon reports a security module among its
+// security options, as "name=<module>" since API 1.30 and "<module>" before
+func lsmEnabled(t Target, name string) bool {
+	for _, opt := range t.Info.SecurityOptions {
+		if opt == name {
+			return true
+		}
+		for _, field := range strings.Split(opt, ",") {
+			if field == "name="+name {
+				return true
+			}
+		}
+	}
+	return false
+}
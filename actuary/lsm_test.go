@@ -0,0 +1,98 @@
+package actuary
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes the loaded AppArmor profiles under BaseDir
+func writeAppArmorProfiles(t *testing.T, target *Target, profiles string) {
+	dir, err := ioutil.TempDir("", "apparmor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.BaseDir = dir
+	fpath := filepath.Join(dir, apparmorProfilesFile)
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fpath, []byte(profiles), 0444); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckAppArmorUnconfined(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{AppArmorProfile: "unconfined"}})
+	testTarget.Info.SecurityOptions = []string{"name=apparmor"}
+	res := CheckAppArmor(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container is unconfined, should not pass.")
+
+	testTarget = newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{AppArmorProfile: "docker-default",
+		HostConfig: &container.HostConfig{SecurityOpt: []string{"apparmor=unconfined"}}}})
+	testTarget.Info.SecurityOptions = []string{"name=apparmor"}
+	res = CheckAppArmor(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container runs with apparmor=unconfined, should not pass.")
+}
+
+func TestCheckAppArmorLoaded(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{AppArmorProfile: "docker-default"}})
+	testTarget.Info.SecurityOptions = []string{"name=apparmor"}
+	writeAppArmorProfiles(t, testTarget, "docker-default (enforce)\nnginx (complain)\n")
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckAppArmor(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Profile is loaded and enforced, should pass.")
+
+	testTarget.Containers[0].Info.AppArmorProfile = "nginx"
+	res = CheckAppArmor(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Profile is in complain mode, should not pass.")
+	assert.Contains(t, res.Output, "profile nginx in complain mode")
+
+	testTarget.Containers[0].Info.AppArmorProfile = "custom"
+	res = CheckAppArmor(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Profile is not loaded, should not pass.")
+	assert.Contains(t, res.Output, "profile custom not loaded")
+}
+
+func TestCheckSELinuxDisabled(t *testing.T) {
+	for _, opt := range []string{"label=disable", "label:disable"} {
+		testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+			ProcessLabel: "system_u:system_r:container_t:s0:c1,c2",
+			HostConfig:   &container.HostConfig{SecurityOpt: []string{opt}}}})
+		testTarget.Info.SecurityOptions = []string{"name=selinux"}
+		res := CheckSELinux(*testTarget)
+		assert.Equal(t, "WARN", res.Status, "Labeling is disabled, should not pass.")
+	}
+}
+
+func TestCheckSELinuxSuperPrivileged(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+		ProcessLabel: "system_u:system_r:spc_t:s0",
+		HostConfig:   &container.HostConfig{SecurityOpt: []string{"label:type:spc_t"}}}})
+	testTarget.Info.SecurityOptions = []string{"name=selinux"}
+	res := CheckSELinux(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container runs as spc_t, should not pass.")
+	assert.Contains(t, res.Output, "unconfined type spc_t")
+}
+
+func TestCheckAppArmorNotEnabled(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{}})
+	testTarget.Info.SecurityOptions = []string{"name=selinux", "name=seccomp,profile=default"}
+	res := CheckAppArmor(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "AppArmor not enabled on the daemon, should skip.")
+}
+
+func TestCheckSELinuxNotEnabled(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{}})
+	testTarget.Info.SecurityOptions = []string{"name=apparmor", "name=seccomp,profile=default"}
+	res := CheckSELinux(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "SELinux not enabled on the daemon, should skip.")
+
+	testTarget.Info.SecurityOptions = []string{"selinux"}
+	res = CheckSELinux(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "SELinux enabled on an older daemon, should not pass.")
+}
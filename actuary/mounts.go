@@ -0,0 +1,167 @@
+/*
+Package checks - 5 Container Runtime (mounts)
+Host paths mounted into containers are matched against the mount policy: some
+paths may not be mounted at all, others only read-only. A path matches a rule
+for the same path or any path below it, except "/", which only matches the
+host root itself.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"golang.org/x/net/context"
+	"path"
+	"strings"
+)
+
+// Sockets of container runtimes, which give full control of the host
+var runtimeSockets = []string{"docker.sock", "containerd.sock", "crio.sock", "dockershim.sock", "podman.sock"}
+
+func CheckSensitiveDirs(t Target) (res Result) {
+	var findings []string
+	res.Name = "5.5 Do not mount sensitive host system directories on containers"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	p := t.Policy.Mounts
+	for _, c := range t.Containers {
+		var matches []string
+		for _, m := range c.Info.Mounts {
+			source := getMountHostPath(t, m)
+			if source == "" {
+				continue
+			}
+			switch {
+			case source == "/":
+				matches = append(matches, "/ (host root)")
+			case isRuntimeSocket(source):
+				matches = append(matches, source+" (runtime socket)")
+			case matchesMountRule(source, p.denied()):
+				matches = append(matches, source+" (denied)")
+			case m.RW && matchesMountRule(source, p.readOnly()):
+				matches = append(matches, source+" (read-write)")
+			}
+		}
+		if len(matches) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, summarize(matches, 10)))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Sensitive host paths mounted on containers: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckTmpfsNoexec(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that tmpfs mounts are mounted noexec"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		var matches []string
+		for dest, opts := range c.Info.HostConfig.Tmpfs {
+			if !stringInSlice("noexec", strings.Split(opts, ",")) {
+				matches = append(matches, dest)
+			}
+		}
+		if len(matches) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, summarize(matches, 10)))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers with executable tmpfs mounts: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckHostPathVolumes(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that named volumes do not bind host paths"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		var matches []string
+		for _, m := range c.Info.Mounts {
+			if m.Type != mount.TypeVolume {
+				continue
+			}
+			if device := getVolumeHostPath(t, m.Name); device != "" {
+				matches = append(matches, fmt.Sprintf("%s binds %s", m.Name, device))
+			}
+		}
+		if len(matches) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, summarize(matches, 10)))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers using volumes bound to host paths: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+// getMountHostPath returns the host path a mount exposes. Named volumes only
+// expose one when their driver binds a host directory.
+func getMountHostPath(t Target, m types.MountPoint) string {
+	if m.Type == mount.TypeVolume {
+		return getVolumeHostPath(t, m.Name)
+	}
+	if m.Source == "" {
+		return ""
+	}
+	return path.Clean(m.Source)
+}
+
+// getVolumeHostPath returns the host directory a local volume created with
+// "-o type=none -o o=bind -o device=<path>" is bound to
+func getVolumeHostPath(t Target, name string) string {
+	if name == "" {
+		return ""
+	}
+	volume, err := t.Client.VolumeInspect(context.TODO(), name)
+	if err != nil || volume.Options["device"] == "" {
+		return ""
+	}
+	if !stringInSlice("bind", strings.Split(volume.Options["o"], ",")) {
+		return ""
+	}
+	return path.Clean(volume.Options["device"])
+}
+
+// matchesMountRule tells whether a host path is one of the rule paths or below one
+func matchesMountRule(source string, rules []string) bool {
+	for _, rule := range rules {
+		rule = path.Clean(rule)
+		if source == rule || (rule != "/" && strings.HasPrefix(source, rule+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRuntimeSocket(source string) bool {
+	return runtimeSocket(source) != ""
+}
+
+// runtimeSocket returns which of the runtime sockets a host path is, or ""
+func runtimeSocket(source string) string {
+	if name := path.Base(source); stringInSlice(name, runtimeSockets) {
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package actuary
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchesMountRule(t *testing.T) {
+	rules := []string{"/usr", "/etc/shadow", "/"}
+	assert.True(t, matchesMountRule("/usr/lib", rules), "/usr/lib is below /usr")
+	assert.True(t, matchesMountRule("/etc/shadow", rules), "/etc/shadow is a rule")
+	assert.True(t, matchesMountRule("/", rules), "/ is a rule")
+	assert.False(t, matchesMountRule("/usrlocal", rules), "/usrlocal is not below /usr")
+	assert.False(t, matchesMountRule("/etc", rules), "/ only matches the host root")
+}
+
+func TestCheckSensitiveDirsPolicy(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{}},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeBind, Source: "/usrlocal/data", RW: true},
+			{Type: mount.TypeBind, Source: "/etc/ssl/certs", RW: false},
+		},
+	})
+	res := CheckSensitiveDirs(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Only read-only system paths mounted, should have passed.")
+
+	testTarget = newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{}},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeBind, Source: "/etc/shadow", RW: false},
+			{Type: mount.TypeBind, Source: "/", RW: false},
+			{Type: mount.TypeBind, Source: "/run/containerd/containerd.sock", RW: true},
+			{Type: mount.TypeBind, Source: "/usr/local/bin", RW: true},
+		},
+	})
+	res = CheckSensitiveDirs(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Sensitive host paths mounted, should not have passed.")
+	for _, finding := range []string{"/etc/shadow (denied)", "/ (host root)", "/run/containerd/containerd.sock (runtime socket)", "/usr/local/bin (read-write)"} {
+		assert.Contains(t, res.Output, finding)
+	}
+
+	testTarget.Policy.Mounts.Denied = []string{}
+	testTarget.Policy.Mounts.ReadOnly = []string{"/opt"}
+	testTarget.Containers[0].Info.Mounts = []types.MountPoint{{Type: mount.TypeBind, Source: "/etc/shadow"}, {Type: mount.TypeBind, Source: "/usr", RW: true}}
+	res = CheckSensitiveDirs(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Policy allows these mounts, should have passed.")
+}
+
+func TestCheckHostPathVolumes(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{}},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeVolume, Name: "data", Source: "/var/lib/docker/volumes/data/_data", RW: true},
+			{Type: mount.TypeVolume, Name: "config", Source: "/var/lib/docker/volumes/config/_data", RW: true},
+		},
+	})
+	data, _ := json.Marshal(types.Volume{Name: "data", Driver: "local"})
+	config, _ := json.Marshal(types.Volume{Name: "config", Driver: "local",
+		Options: map[string]string{"type": "none", "o": "bind", "device": "/etc/"}})
+	ts := testTarget.testServer(t, callPairing{"/volumes/data", data}, callPairing{"/volumes/config", config})
+	defer ts.Close()
+
+	res := CheckHostPathVolumes(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Volume bound to a host path, should not have passed.")
+	assert.Equal(t, "Containers using volumes bound to host paths: Container_id1: config binds /etc", res.Output)
+
+	res = CheckSensitiveDirs(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Volume exposes /etc read-write, should not have passed.")
+	assert.Contains(t, res.Output, "/etc (read-write)")
+}
+
+func TestCheckTmpfsNoexec(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{}},
+	})
+	testTarget.Containers[0].Info.HostConfig.Tmpfs = map[string]string{"/tmp": "rw,noexec,nosuid,size=65536k"}
+	res := CheckTmpfsNoexec(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "tmpfs mounted noexec, should have passed.")
+
+	testTarget.Containers[0].Info.HostConfig.Tmpfs["/run"] = ""
+	res = CheckTmpfsNoexec(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "tmpfs mounted without noexec, should not have passed.")
+	assert.Contains(t, res.Output, "/run")
+}
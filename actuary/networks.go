@@ -0,0 +1,132 @@
+/*
+Package checks - 5 Container Runtime (networks)
+Every network the daemon knows is inspected, rather than the default bridge
+only, so that user-defined networks and the containers attached to them are
+covered as well.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+	"sort"
+	"strings"
+)
+
+func CheckContainerNetworkCount(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that containers are not attached to many networks"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	max := t.Policy.Networks.maxNetworksPerContainer()
+	for _, c := range t.Containers {
+		if c.Info.NetworkSettings == nil || len(c.Info.NetworkSettings.Networks) <= max {
+			continue
+		}
+		var names []string
+		for name := range c.Info.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		findings = append(findings, fmt.Sprintf("%s: %d networks (%s)", c.ID, len(names), strings.Join(names, ", ")))
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail(fmt.Sprintf("Containers attached to more than %d networks: %s", max, strings.Join(findings, "; ")))
+	}
+	return
+}
+
+func CheckInternalNetworks(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that backend networks are internal"
+	patterns := t.Policy.Networks.Internal
+	if len(patterns) == 0 {
+		res.Skip("No internal networks configured")
+		return
+	}
+	networks, err := getNetworks(t)
+	if err != nil {
+		res.Skip("Cannot retrieve network list")
+		return
+	}
+	for _, network := range networks {
+		if network.Internal {
+			continue
+		}
+		for _, pattern := range patterns {
+			if globMatch(pattern, network.Name) {
+				findings = append(findings, describeNetwork(network))
+				break
+			}
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Backend networks with external access: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckSharedNetNamespace(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that containers do not share another container's network namespace"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		if mode := c.Info.HostConfig.NetworkMode; mode.IsContainer() {
+			findings = append(findings, fmt.Sprintf("%s: shares the network of %s", c.ID, mode.ConnectedContainer()))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers sharing a network namespace: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+// getNetworks lists every network, inspecting each one to learn the containers
+// attached to it. A network that cannot be inspected is kept as listed.
+func getNetworks(t Target) (networks []types.NetworkResource, err error) {
+	listed, err := t.Client.NetworkList(context.TODO(), types.NetworkListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, network := range listed {
+		if network.ID != "" {
+			if inspected, err := t.Client.NetworkInspect(context.TODO(), network.ID, types.NetworkInspectOptions{}); err == nil {
+				network = inspected
+			}
+		}
+		networks = append(networks, network)
+	}
+	return
+}
+
+// describeNetwork names a network and the containers attached to it
+func describeNetwork(network types.NetworkResource) string {
+	var names []string
+	for id, endpoint := range network.Containers {
+		name := endpoint.Name
+		if name == "" {
+			name = id
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return network.Name
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%s (containers: %s)", network.Name, summarize(names, 10))
+}
@@ -0,0 +1,93 @@
+package actuary
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Serves the network list and the inspect output of each network
+func networkTestPairings(t *testing.T, networks []types.NetworkResource) (pairings []callPairing) {
+	listed := make([]types.NetworkResource, len(networks))
+	for i, n := range networks {
+		// Containers are only reported by inspect
+		listed[i] = n
+		listed[i].Containers = nil
+		nJSON, err := json.Marshal(n)
+		if err != nil {
+			t.Errorf("Could not convert network to json.")
+		}
+		pairings = append(pairings, callPairing{"/networks/" + n.ID, nJSON})
+	}
+	nJSON, err := json.Marshal(listed)
+	if err != nil {
+		t.Errorf("Could not convert networks to json.")
+	}
+	return append(pairings, callPairing{"/networks", nJSON})
+}
+
+func TestRestrictNetTrafficUserDefined(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	networks := []types.NetworkResource{
+		{ID: "n1", Name: "bridge", Driver: "bridge", Options: map[string]string{"com.docker.network.bridge.enable_icc": "false"}},
+		{ID: "n2", Name: "app", Driver: "bridge", Containers: map[string]types.EndpointResource{"c1": {Name: "web"}, "c2": {Name: "db"}}},
+	}
+	ts := testTarget.testServer(t, networkTestPairings(t, networks)...)
+	defer ts.Close()
+	res := RestrictNetTraffic(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "User-defined bridge with ICC enabled, should not pass.")
+	assert.Contains(t, res.Output, "app (containers: db, web)")
+}
+
+func TestCheckInternalNetworks(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	networks := []types.NetworkResource{
+		{ID: "n1", Name: "backend-db", Driver: "bridge", Internal: true},
+		{ID: "n2", Name: "backend-cache", Driver: "bridge"},
+		{ID: "n3", Name: "frontend", Driver: "bridge"},
+	}
+	ts := testTarget.testServer(t, networkTestPairings(t, networks)...)
+	defer ts.Close()
+	res := CheckInternalNetworks(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No internal networks configured, should skip.")
+
+	testTarget.Policy.Networks.Internal = []string{"backend-*"}
+	res = CheckInternalNetworks(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Backend network is not internal, should not pass.")
+	assert.Equal(t, "Backend networks with external access: backend-cache", res.Output)
+}
+
+func TestCheckContainerNetworkCount(t *testing.T) {
+	settings := &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{"a": {}, "b": {}}}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{},
+		NetworkSettings:   settings,
+	})
+	res := CheckContainerNetworkCount(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container attached to two networks, should pass.")
+
+	settings.Networks["c"] = &network.EndpointSettings{}
+	res = CheckContainerNetworkCount(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container attached to three networks, should not pass.")
+}
+
+func TestCheckSharedNetNamespace(t *testing.T) {
+	base := &types.ContainerJSONBase{HostConfig: &container.HostConfig{NetworkMode: "bridge"}}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: base})
+	res := CheckSharedNetNamespace(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container has its own network namespace, should pass.")
+
+	base.HostConfig.NetworkMode = "container:proxy"
+	res = CheckSharedNetNamespace(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container shares the network of another container, should not pass.")
+	assert.Contains(t, res.Output, "shares the network of proxy")
+}
@@ -0,0 +1,308 @@
+/*
+Package checks - 4 Container Images and Build File (installed packages)
+The package databases of Debian and Alpine based images are read from the image
+filesystem. RPM databases are stored in Berkeley DB or SQLite files which cannot
+be read without native libraries, so RPM based images are not covered.
+*/
+package actuary
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+const (
+	dpkgStatusFile = "/var/lib/dpkg/status"
+	// Distroless images keep one status file per package instead
+	dpkgStatusDir    = "/var/lib/dpkg/status.d/"
+	apkInstalledFile = "/lib/apk/db/installed"
+)
+
+var osReleaseFiles = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// imagePackage is an installed OS package
+type imagePackage struct {
+	Name    string
+	Version string
+	// Source is the source package the package was built from, which is the
+	// name advisories refer to
+	Source string
+}
+
+// imageDistro identifies the distribution an image is based on
+type imageDistro struct {
+	ID      string
+	Version string
+}
+
+// isCapturedFile tells whether the content of an image file is kept when the
+// image is scanned
+func isCapturedFile(name string) bool {
+	return name == dpkgStatusFile || strings.HasPrefix(name, dpkgStatusDir) ||
+		name == apkInstalledFile || stringInSlice(name, osReleaseFiles) ||
+		isLanguageManifest(name)
+}
+
+// getImageDistro parses os-release. /etc/os-release is usually a symlink, in
+// which case the file it points to is read.
+func getImageDistro(fs imageFS) (distro imageDistro) {
+	for _, name := range osReleaseFiles {
+		content := fs[name].Content
+		if content == nil {
+			continue
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			val := strings.Trim(kv[1], `"'`)
+			switch kv[0] {
+			case "ID":
+				distro.ID = val
+			case "VERSION_ID":
+				distro.Version = val
+			}
+		}
+		return
+	}
+	return
+}
+
+// getImagePackages returns the packages recorded by dpkg or apk
+func getImagePackages(fs imageFS) (pkgs []imagePackage) {
+	for _, name := range fs.paths() {
+		content := fs[name].Content
+		switch {
+		case content == nil:
+		case name == dpkgStatusFile || strings.HasPrefix(name, dpkgStatusDir):
+			pkgs = append(pkgs, parseDpkgStatus(content)...)
+		case name == apkInstalledFile:
+			pkgs = append(pkgs, parseApkInstalled(content)...)
+		}
+	}
+	return
+}
+
+// parseStanzas splits a file made of blank line separated blocks of
+// "Key<sep>value" lines. Continuation lines are ignored.
+func parseStanzas(content []byte, sep string) (stanzas []map[string]string) {
+	stanza := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), maxCapturedFileSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(stanza) != 0 {
+				stanzas = append(stanzas, stanza)
+				stanza = make(map[string]string)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if kv := strings.SplitN(line, sep, 2); len(kv) == 2 {
+			stanza[kv[0]] = strings.TrimSpace(kv[1])
+		}
+	}
+	if len(stanza) != 0 {
+		stanzas = append(stanzas, stanza)
+	}
+	return
+}
+
+func parseDpkgStatus(content []byte) (pkgs []imagePackage) {
+	for _, stanza := range parseStanzas(content, ":") {
+		// Packages that were removed but left config files behind are
+		// still listed, with a different status
+		if status, ok := stanza["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		pkg := imagePackage{Name: stanza["Package"], Version: stanza["Version"]}
+		// "Source: name (version)" when the source version differs
+		pkg.Source = strings.Fields(stanza["Source"] + " " + pkg.Name)[0]
+		if pkg.Name != "" && pkg.Version != "" {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return
+}
+
+func parseApkInstalled(content []byte) (pkgs []imagePackage) {
+	for _, stanza := range parseStanzas(content, ":") {
+		pkg := imagePackage{Name: stanza["P"], Version: stanza["V"], Source: stanza["o"]}
+		if pkg.Source == "" {
+			pkg.Source = pkg.Name
+		}
+		if pkg.Name != "" && pkg.Version != "" {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return
+}
+
+// compareDpkgVersions compares two Debian package versions as dpkg does,
+// returning -1, 0 or 1
+func compareDpkgVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitDpkgVersion(a)
+	bEpoch, bUpstream, bRevision := splitDpkgVersion(b)
+	if aEpoch != bEpoch {
+		if aEpoch < bEpoch {
+			return -1
+		}
+		return 1
+	}
+	if c := compareDpkgPart(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return compareDpkgPart(aRevision, bRevision)
+}
+
+func splitDpkgVersion(v string) (epoch int, upstream, revision string) {
+	if i := strings.Index(v, ":"); i > 0 {
+		if e, err := strconv.Atoi(v[:i]); err == nil {
+			epoch = e
+			v = v[i+1:]
+		}
+	}
+	upstream = v
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		upstream, revision = v[:i], v[i+1:]
+	}
+	return
+}
+
+// compareDpkgPart alternately compares runs of non-digits, where '~' sorts
+// before anything and letters before other characters, and runs of digits
+func compareDpkgPart(a, b string) int {
+	for a != "" || b != "" {
+		var aText, bText string
+		aText, a = splitRun(a, false)
+		bText, b = splitRun(b, false)
+		for i := 0; i < len(aText) || i < len(bText); i++ {
+			if ac, bc := dpkgOrder(aText, i), dpkgOrder(bText, i); ac != bc {
+				if ac < bc {
+					return -1
+				}
+				return 1
+			}
+		}
+		var aNum, bNum string
+		aNum, a = splitRun(a, true)
+		bNum, b = splitRun(b, true)
+		if c := compareNumeric(aNum, bNum); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func dpkgOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case c == '~':
+		return -1
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+// splitRun splits off the leading run of digits or non-digits
+func splitRun(s string, digits bool) (run, rest string) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumeric compares strings of digits of any length
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Ranks of apk version suffixes; a version without suffix ranks as "release"
+var apkSuffixes = map[string]int{"alpha": 0, "beta": 1, "pre": 2, "rc": 3, "release": 4,
+	"cvs": 5, "svn": 6, "git": 7, "hg": 8, "p": 9}
+
+type apkVersion struct {
+	Numbers  []string
+	Letter   byte
+	Suffixes [][2]string
+	Revision string
+}
+
+func parseApkVersion(v string) (ver apkVersion) {
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		ver.Revision = v[i+2:]
+		v = v[:i]
+	}
+	parts := strings.Split(v, "_")
+	ver.Numbers = strings.Split(parts[0], ".")
+	last := ver.Numbers[len(ver.Numbers)-1]
+	if n := len(last); n > 0 && (last[n-1] < '0' || last[n-1] > '9') {
+		ver.Letter = last[n-1]
+		ver.Numbers[len(ver.Numbers)-1] = last[:n-1]
+	}
+	for _, suffix := range parts[1:] {
+		name, num := splitRun(suffix, false)
+		ver.Suffixes = append(ver.Suffixes, [2]string{name, num})
+	}
+	return
+}
+
+// compareApkVersions compares two Alpine package versions, returning -1, 0 or 1
+func compareApkVersions(a, b string) int {
+	av, bv := parseApkVersion(a), parseApkVersion(b)
+	for i := 0; i < len(av.Numbers) || i < len(bv.Numbers); i++ {
+		if i >= len(av.Numbers) {
+			return -1
+		}
+		if i >= len(bv.Numbers) {
+			return 1
+		}
+		if c := compareNumeric(av.Numbers[i], bv.Numbers[i]); c != 0 {
+			return c
+		}
+	}
+	if av.Letter != bv.Letter {
+		if av.Letter < bv.Letter {
+			return -1
+		}
+		return 1
+	}
+	for i := 0; i < len(av.Suffixes) || i < len(bv.Suffixes); i++ {
+		as, bs := [2]string{"release", ""}, [2]string{"release", ""}
+		if i < len(av.Suffixes) {
+			as = av.Suffixes[i]
+		}
+		if i < len(bv.Suffixes) {
+			bs = bv.Suffixes[i]
+		}
+		if ar, br := apkSuffixes[as[0]], apkSuffixes[bs[0]]; ar != br {
+			if ar < br {
+				return -1
+			}
+			return 1
+		}
+		if c := compareNumeric(as[1], bs[1]); c != 0 {
+			return c
+		}
+	}
+	return compareNumeric(av.Revision, bv.Revision)
+}
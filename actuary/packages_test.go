@@ -0,0 +1,77 @@
+package actuary
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompareDpkgVersions(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1:1.0", "2.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.1.1k-1+deb11u1", "1.1.1n-0+deb11u1", -1},
+		{"2.31-13+deb11u5", "2.31-13+deb11u3", 1},
+		{"1.10", "1.9", 1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, compareDpkgVersions(c.a, c.b), c.a+" vs "+c.b)
+	}
+}
+
+func TestCompareApkVersions(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3-r0", "1.2.3-r0", 0},
+		{"1.2.3-r0", "1.2.3-r1", -1},
+		{"1.2.3_rc1-r0", "1.2.3-r0", -1},
+		{"1.2.3_p1-r0", "1.2.3-r0", 1},
+		{"1.1.1q-r0", "1.1.1t-r0", -1},
+		{"1.10-r0", "1.9-r0", 1},
+		{"1.2-r0", "1.2.1-r0", -1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, compareApkVersions(c.a, c.b), c.a+" vs "+c.b)
+	}
+}
+
+func TestParsePackageDatabases(t *testing.T) {
+	dpkg := parseDpkgStatus([]byte(`Package: libssl1.1
+Status: install ok installed
+Source: openssl
+Version: 1.1.1k-1
+Description: SSL libraries
+ continuation line: not a field
+
+Package: old
+Status: deinstall ok config-files
+Version: 1.0
+
+Package: zlib1g
+Status: install ok installed
+Source: zlib (1:1.2.11.dfsg-2)
+Version: 1:1.2.11.dfsg-2+deb11u1
+`))
+	assert.Equal(t, []imagePackage{
+		{Name: "libssl1.1", Version: "1.1.1k-1", Source: "openssl"},
+		{Name: "zlib1g", Version: "1:1.2.11.dfsg-2+deb11u1", Source: "zlib"},
+	}, dpkg)
+	apk := parseApkInstalled([]byte(`C:Q1abc=
+P:libcrypto1.1
+V:1.1.1q-r0
+o:openssl
+
+P:musl
+V:1.2.3-r0
+`))
+	assert.Equal(t, []imagePackage{
+		{Name: "libcrypto1.1", Version: "1.1.1q-r0", Source: "openssl"},
+		{Name: "musl", Version: "1.2.3-r0", Source: "musl"},
+	}, apk)
+}
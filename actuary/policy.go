@@ -0,0 +1,400 @@
+package actuary
+
+import (
+	"path"
+	"strings"
+)
+
+// Policy holds the profile-supplied settings that tune individual checks.
+// Zero values fall back to the defaults documented on each field.
+type Policy struct {
+	TLS             TLSPolicy
+	Swarm           SwarmPolicy
+	Images          ImagePolicy
+	Vulnerabilities VulnerabilityPolicy
+	Freshness       FreshnessPolicy
+	// Capabilities restricts the capabilities of selected containers, see
+	// CheckKernelCapabilities
+	Capabilities []CapabilityRule
+	Seccomp      SeccompPolicy
+	Processes    ProcessPolicy
+	Networks     NetworkPolicy
+	Mounts       MountPolicy
+	Logging      LoggingPolicy
+	Health       HealthPolicy
+	Hygiene      HygienePolicy
+	// Resources bounds the resource limits of selected containers, see
+	// CheckResourcePolicy
+	Resources []ResourceRule
+}
+
+// TLSPolicy configures the certificate quality checks
+type TLSPolicy struct {
+	// ExpiryWarningDays flags certificates expiring within this many days (default 30)
+	ExpiryWarningDays int
+	// MinRSABits is the smallest acceptable RSA modulus (default 2048)
+	MinRSABits int
+	// MinECBits is the smallest acceptable elliptic curve size (default 256)
+	MinECBits int
+}
+
+func (p TLSPolicy) expiryWarningDays() int {
+	if p.ExpiryWarningDays == 0 {
+		return 30
+	}
+	return p.ExpiryWarningDays
+}
+
+func (p TLSPolicy) minRSABits() int {
+	if p.MinRSABits == 0 {
+		return 2048
+	}
+	return p.MinRSABits
+}
+
+func (p TLSPolicy) minECBits() int {
+	if p.MinECBits == 0 {
+		return 256
+	}
+	return p.MinECBits
+}
+
+// SwarmPolicy configures the swarm checks
+type SwarmPolicy struct {
+	// MaxNodeCertExpiryDays is the longest acceptable node certificate validity (default 90)
+	MaxNodeCertExpiryDays int
+	// MaxCAAgeDays is the age after which the root CA should be rotated (default 365)
+	MaxCAAgeDays int
+	// StaleNodeHours is how long a node may be down before it is reported (default 24)
+	StaleNodeHours int
+}
+
+func (p SwarmPolicy) maxNodeCertExpiryDays() int {
+	if p.MaxNodeCertExpiryDays == 0 {
+		return 90
+	}
+	return p.MaxNodeCertExpiryDays
+}
+
+func (p SwarmPolicy) maxCAAgeDays() int {
+	if p.MaxCAAgeDays == 0 {
+		return 365
+	}
+	return p.MaxCAAgeDays
+}
+
+func (p SwarmPolicy) staleNodeHours() int {
+	if p.StaleNodeHours == 0 {
+		return 24
+	}
+	return p.StaleNodeHours
+}
+
+// ImagePolicy configures the image checks
+type ImagePolicy struct {
+	// AllowedSetuid lists setuid and setgid files that images may keep, e.g. "/usr/bin/passwd"
+	AllowedSetuid []string
+	// AllowedRegistries lists the registries images may come from, e.g. "docker.io"
+	AllowedRegistries []string
+	// DigestAllowlist is a file listing approved image digests, one per line.
+	// It must be signed: DigestAllowlistSignature holds the signature and
+	// DigestAllowlistKey the PEM encoded public key verifying it.
+	DigestAllowlist          string
+	DigestAllowlistSignature string
+	DigestAllowlistKey       string
+}
+
+// VulnerabilityPolicy configures the package vulnerability check
+type VulnerabilityPolicy struct {
+	// OSVPath is a directory of OSV advisories in JSON format, or a single
+	// file. The check is skipped when it is not set.
+	OSVPath string
+}
+
+// FreshnessPolicy configures the image and container age checks
+type FreshnessPolicy struct {
+	// MaxImageAgeDays is the age after which an image should be rebuilt (default 90)
+	MaxImageAgeDays int
+	// MaxUptimeDays is how long a container may run before it is recreated (default 30)
+	MaxUptimeDays int
+}
+
+func (p FreshnessPolicy) maxImageAgeDays() int {
+	if p.MaxImageAgeDays == 0 {
+		return 90
+	}
+	return p.MaxImageAgeDays
+}
+
+func (p FreshnessPolicy) maxUptimeDays() int {
+	if p.MaxUptimeDays == 0 {
+		return 30
+	}
+	return p.MaxUptimeDays
+}
+
+// SeccompPolicy configures the seccomp profile checks
+type SeccompPolicy struct {
+	// Baseline is a seccomp profile in Docker's JSON format that containers
+	// should not allow more than. The baseline check is skipped when it is not set.
+	Baseline string
+}
+
+// ProcessPolicy configures the forbidden process check. Entries are shell
+// patterns matched against program names, e.g. "sshd" or "python*".
+type ProcessPolicy struct {
+	// Denied lists programs that should not run in containers (default sshd,
+	// cron and package managers)
+	Denied []string
+	// DeniedInit lists programs that should not run as a container's init
+	// process (default shells)
+	DeniedInit []string
+}
+
+func (p ProcessPolicy) denied() []string {
+	if p.Denied == nil {
+		return []string{"sshd", "cron", "crond", "anacron", "apt", "apt-get", "aptitude", "dpkg",
+			"yum", "dnf", "microdnf", "rpm", "zypper", "apk"}
+	}
+	return p.Denied
+}
+
+func (p ProcessPolicy) deniedInit() []string {
+	if p.DeniedInit == nil {
+		return []string{"sh", "bash", "ash", "dash", "zsh", "ksh", "csh", "tcsh", "fish"}
+	}
+	return p.DeniedInit
+}
+
+// NetworkPolicy configures the network checks
+type NetworkPolicy struct {
+	// MaxNetworksPerContainer is the number of networks a container may be
+	// attached to (default 2)
+	MaxNetworksPerContainer int
+	// Internal lists patterns of network names that must be created with
+	// --internal, e.g. "backend-*"
+	Internal []string
+}
+
+func (p NetworkPolicy) maxNetworksPerContainer() int {
+	if p.MaxNetworksPerContainer == 0 {
+		return 2
+	}
+	return p.MaxNetworksPerContainer
+}
+
+// MountPolicy configures the sensitive host path check. A rule matches the
+// path itself and everything below it.
+type MountPolicy struct {
+	// Denied lists host paths that may not be mounted at all (default
+	// secrets such as /etc/shadow, /root and Docker's own state)
+	Denied []string
+	// ReadOnly lists host paths that may only be mounted read-only (default
+	// the system directories, e.g. /etc and /usr)
+	ReadOnly []string
+}
+
+func (p MountPolicy) denied() []string {
+	if p.Denied == nil {
+		return []string{"/etc/shadow", "/etc/gshadow", "/etc/sudoers", "/etc/sudoers.d",
+			"/etc/ssh", "/root", "/var/lib/docker", "/var/lib/containerd", "/boot"}
+	}
+	return p.Denied
+}
+
+func (p MountPolicy) readOnly() []string {
+	if p.ReadOnly == nil {
+		return []string{"/bin", "/dev", "/etc", "/lib", "/lib64", "/proc", "/sbin", "/sys", "/usr"}
+	}
+	return p.ReadOnly
+}
+
+// LoggingPolicy configures the container logging checks
+type LoggingPolicy struct {
+	// AllowedDrivers lists the log drivers containers may use, e.g. "syslog".
+	// The log driver check is skipped when it is not set.
+	AllowedDrivers []string
+	// MaxLogSize is the size above which a json-file log is reported (default "100m")
+	MaxLogSize string
+}
+
+func (p LoggingPolicy) maxLogSize() string {
+	if p.MaxLogSize == "" {
+		return "100m"
+	}
+	return p.MaxLogSize
+}
+
+// HealthPolicy configures the container health and stability checks
+type HealthPolicy struct {
+	// MaxRestartCount is how many times a container may have been restarted
+	// before it is reported as restart looping (default 5)
+	MaxRestartCount int
+	// RestartWindowHours is how recently a container restarted more than
+	// MaxRestartCount times must have been started again to be reported
+	// (default 1)
+	RestartWindowHours int
+	// MaxExitedHours is how long a container may be left exited with a
+	// non-zero code (default 24)
+	MaxExitedHours int
+}
+
+func (p HealthPolicy) maxRestartCount() int {
+	if p.MaxRestartCount == 0 {
+		return 5
+	}
+	return p.MaxRestartCount
+}
+
+func (p HealthPolicy) restartWindowHours() int {
+	if p.RestartWindowHours == 0 {
+		return 1
+	}
+	return p.RestartWindowHours
+}
+
+func (p HealthPolicy) maxExitedHours() int {
+	if p.MaxExitedHours == 0 {
+		return 24
+	}
+	return p.MaxExitedHours
+}
+
+// HygienePolicy configures the image and container sprawl checks, and the
+// checks of the resources left unused on the host
+type HygienePolicy struct {
+	// MaxImages is the number of images the host may keep (default 100)
+	MaxImages int
+	// MaxStoppedContainers is the number of stopped containers the host may
+	// keep (default 25)
+	MaxStoppedContainers int
+	// MaxDanglingImages is the number of untagged images the host may keep (default 10)
+	MaxDanglingImages int
+	// MaxUnusedImages is the number of images no container uses the host may
+	// keep (default 50)
+	MaxUnusedImages int
+	// MaxUnusedVolumes is the number of volumes no container uses the host
+	// may keep (default 10)
+	MaxUnusedVolumes int
+	// MaxUnusedNetworks is the number of user-defined networks no container
+	// is attached to the host may keep (default 10)
+	MaxUnusedNetworks int
+	// MaxBuildCacheSize is the size the build cache may grow to (default "10g")
+	MaxBuildCacheSize string
+}
+
+func (p HygienePolicy) maxImages() int {
+	if p.MaxImages == 0 {
+		return 100
+	}
+	return p.MaxImages
+}
+
+func (p HygienePolicy) maxStoppedContainers() int {
+	if p.MaxStoppedContainers == 0 {
+		return 25
+	}
+	return p.MaxStoppedContainers
+}
+
+func (p HygienePolicy) maxDanglingImages() int {
+	if p.MaxDanglingImages == 0 {
+		return 10
+	}
+	return p.MaxDanglingImages
+}
+
+func (p HygienePolicy) maxUnusedImages() int {
+	if p.MaxUnusedImages == 0 {
+		return 50
+	}
+	return p.MaxUnusedImages
+}
+
+func (p HygienePolicy) maxUnusedVolumes() int {
+	if p.MaxUnusedVolumes == 0 {
+		return 10
+	}
+	return p.MaxUnusedVolumes
+}
+
+func (p HygienePolicy) maxUnusedNetworks() int {
+	if p.MaxUnusedNetworks == 0 {
+		return 10
+	}
+	return p.MaxUnusedNetworks
+}
+
+func (p HygienePolicy) maxBuildCacheSize() string {
+	if p.MaxBuildCacheSize == "" {
+		return "10g"
+	}
+	return p.MaxBuildCacheSize
+}
+
+// ContainerSelector picks containers by name, image reference or label. Name
+// and Image are shell patterns, e.g. "web-*", and Label is "key" or
+// "key=value". Empty fields match every container.
+type ContainerSelector struct {
+	Name  string
+	Image string
+	Label string
+}
+
+func (s ContainerSelector) matches(c Container) bool {
+	info := c.Info
+	if s.Name != "" {
+		if info.ContainerJSONBase == nil || !globMatch(s.Name, strings.TrimPrefix(info.Name, "/")) {
+			return false
+		}
+	}
+	if s.Image != "" {
+		if info.Config == nil || !globMatch(s.Image, info.Config.Image) {
+			return false
+		}
+	}
+	if s.Label != "" {
+		if info.Config == nil {
+			return false
+		}
+		kv := strings.SplitN(s.Label, "=", 2)
+		val, ok := info.Config.Labels[kv[0]]
+		if !ok || (len(kv) == 2 && val != kv[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func globMatch(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// CapabilityRule lists the capabilities the containers picked by its selector
+// may hold, without the CAP_ prefix, e.g. "NET_BIND_SERVICE"
+type CapabilityRule struct {
+	ContainerSelector
+	Allowed []string
+}
+
+// ResourceRule bounds the resource limits of the containers picked by its
+// selector. Zero values leave a limit unchecked. Sizes take units, e.g. "512m".
+type ResourceRule struct {
+	ContainerSelector
+	MinMemory string
+	MaxMemory string
+	// MaxMemorySwap bounds memory and swap together
+	MaxMemorySwap string
+	MinCPUs       float64
+	MaxCPUs       float64
+	// MaxCpusetCPUs is the number of CPUs containers may be pinned to
+	MaxCpusetCPUs  int
+	MinPidsLimit   int64
+	MaxPidsLimit   int64
+	MinBlkioWeight uint16
+	MaxBlkioWeight uint16
+	// MinUlimits and MaxUlimits bound hard ulimits by name, e.g. "nofile"
+	MinUlimits map[string]int64
+	MaxUlimits map[string]int64
+}
@@ -0,0 +1,140 @@
+/*
+Package checks - 5 Container Runtime (processes)
+Containers should run a single service. The processes of each container are
+listed through the daemon and their commands matched against a denylist.
+*/
+package actuary
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Arguments passed to ps by ContainerTop. The daemon only keeps the processes
+// of the container, so every process has to be listed.
+var topArgs = []string{"-eo", "user,pid,ppid,args"}
+
+// containerProcess is a process running in a container
+type containerProcess struct {
+	User    string
+	PID     int
+	PPID    int
+	Command string
+	// Init is set for the container's init process
+	Init bool
+}
+
+func CheckSSHRunning(t Target) (res Result) {
+	res.Name = "5.6 Do not run ssh within containers"
+	auditProcesses(t, &res, func(proc containerProcess) bool {
+		return globMatch("sshd", commandName(proc.Command))
+	})
+	return
+}
+
+func CheckForbiddenProcesses(t Target) (res Result) {
+	res.Name = "Verify that containers run no forbidden processes"
+	p := t.Policy.Processes
+	auditProcesses(t, &res, func(proc containerProcess) bool {
+		name := commandName(proc.Command)
+		for _, pattern := range p.denied() {
+			if globMatch(pattern, name) {
+				return true
+			}
+		}
+		if proc.Init {
+			for _, pattern := range p.deniedInit() {
+				if globMatch(pattern, name) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	return
+}
+
+// auditProcesses lists the processes of every container and fails the result
+// with the ones matched by denied
+func auditProcesses(t Target, res *Result, denied func(proc containerProcess) bool) {
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	t.Containers.auditContainers(res, func(c Container) (matches []string, err error) {
+		procs, err := getContainerProcesses(t, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, proc := range procs {
+			if !denied(proc) {
+				continue
+			}
+			desc := fmt.Sprintf("%s (pid %d, user %s)", proc.Command, proc.PID, proc.User)
+			if proc.Init {
+				desc = fmt.Sprintf("%s as init (pid %d, user %s)", proc.Command, proc.PID, proc.User)
+			}
+			matches = append(matches, desc)
+		}
+		return
+	}, "Containers running forbidden processes: ", "Unable to list container processes")
+}
+
+// getContainerProcesses lists the processes of a container. Columns are found
+// by title, as daemons that cannot pass arguments to ps use their own.
+func getContainerProcesses(t Target, c Container) (procs []containerProcess, err error) {
+	top, err := t.Client.ContainerTop(context.TODO(), c.ID, topArgs)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, title := range top.Titles {
+		columns[strings.ToUpper(title)] = i
+	}
+	column := func(fields []string, titles ...string) string {
+		for _, title := range titles {
+			if i, ok := columns[title]; ok && i < len(fields) {
+				return fields[i]
+			}
+		}
+		return ""
+	}
+	pids := make(map[int]bool)
+	for _, fields := range top.Processes {
+		proc := containerProcess{
+			User:    column(fields, "USER", "UID"),
+			Command: column(fields, "COMMAND", "CMD", "ARGS"),
+		}
+		proc.PID, _ = strconv.Atoi(column(fields, "PID"))
+		proc.PPID, _ = strconv.Atoi(column(fields, "PPID"))
+		pids[proc.PID] = true
+		procs = append(procs, proc)
+	}
+	// The init process is the one the daemon started, or else the one whose
+	// parent is outside the container
+	initPID := 0
+	if c.Info.ContainerJSONBase != nil && c.Info.State != nil {
+		initPID = c.Info.State.Pid
+	}
+	for i := range procs {
+		if initPID != 0 {
+			procs[i].Init = procs[i].PID == initPID
+		} else {
+			procs[i].Init = !pids[procs[i].PPID]
+		}
+	}
+	return
+}
+
+// commandName returns the program name of a command line: "/usr/sbin/sshd -D"
+// and "sshd: user@pts/0" both give "sshd", and login shells lose their "-"
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(path.Base(fields[0]), "-"), ":")
+}
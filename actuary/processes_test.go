@@ -0,0 +1,77 @@
+package actuary
+
+import (
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func processTestTarget(t *testing.T, procs [][]string) (*Target, *httptest.Server) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{})
+	top := container.ContainerTopOKBody{
+		Titles:    []string{"USER", "PID", "PPID", "COMMAND"},
+		Processes: procs,
+	}
+	topJSON, err := json.Marshal(top)
+	if err != nil {
+		t.Errorf("Could not convert process list to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/containers/" + testTarget.Containers[0].ID + "/top", topJSON})
+	return testTarget, ts
+}
+
+func TestCommandName(t *testing.T) {
+	for command, name := range map[string]string{
+		"/usr/sbin/sshd -D":      "sshd",
+		"sshd: app@pts/0":        "sshd",
+		"-bash":                  "bash",
+		"nginx: master":          "nginx",
+		"python3 -m http.server": "python3",
+		"":                       "",
+	} {
+		assert.Equal(t, name, commandName(command), "Wrong program name for %q", command)
+	}
+}
+
+func TestCheckForbiddenProcessesSuccess(t *testing.T) {
+	testTarget, ts := processTestTarget(t, [][]string{
+		{"root", "4100", "4080", "nginx: master process nginx -g daemon off;"},
+		{"nginx", "4120", "4100", "nginx: worker process"},
+		{"root", "4200", "4100", "/bin/sh -c logrotate"},
+	})
+	defer ts.Close()
+	res := CheckForbiddenProcesses(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Shells are only forbidden as init, should have passed.")
+}
+
+func TestCheckForbiddenProcessesFail(t *testing.T) {
+	testTarget, ts := processTestTarget(t, [][]string{
+		{"root", "4100", "4080", "/bin/bash"},
+		{"app", "4120", "4100", "apt-get install curl"},
+	})
+	defer ts.Close()
+	res := CheckForbiddenProcesses(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Shell as init and a package manager, should not have passed.")
+	assert.Contains(t, res.Output, "/bin/bash as init (pid 4100, user root)")
+	assert.Contains(t, res.Output, "apt-get install curl (pid 4120, user app)")
+
+	// The daemon reports the init process when it is known
+	testTarget.Containers[0].Info = ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{State: &types.ContainerState{Pid: 4120}}, nil, nil, nil}}
+	testTarget.Policy.Processes.Denied = []string{"python*"}
+	res = CheckForbiddenProcesses(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Neither process is denied by the policy, should have passed.")
+}
+
+func TestCheckForbiddenProcessesTopError(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	ts := testTarget.testServer(t)
+	defer ts.Close()
+	res := CheckForbiddenProcesses(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "Processes cannot be listed, should skip.")
+}
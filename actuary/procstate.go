@@ -0,0 +1,266 @@
+/*
+Package checks - 5 Container Runtime (process state)
+Inspect reports how a container was configured, not what the kernel enforces.
+These checks read the state of each container's init process from the host's
+/proc and report where it differs from the configuration or from the policy.
+They need access to the host PID namespace; with a BaseDir set, /proc is read
+below it.
+*/
+package actuary
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Filesystems the runtime mounts itself, whose mount root says nothing about
+// the host directory they come from
+var virtualFilesystems = []string{"overlay", "proc", "sysfs", "tmpfs", "devpts", "mqueue",
+	"cgroup", "cgroup2", "shm", "securityfs", "debugfs", "tracefs", "fusectl", "nsfs"}
+
+// Files the runtime bind mounts from its own state directory
+var runtimeMountPoints = []string{"/etc/hosts", "/etc/hostname", "/etc/resolv.conf"}
+
+// procStatus holds the security relevant fields of /proc/<pid>/status
+type procStatus struct {
+	CapEff     []string
+	NoNewPrivs bool
+	// Seccomp is 0 when disabled, 1 in strict mode and 2 in filter mode
+	Seccomp int
+	// EffectiveUID is the effective UID in the host user namespace
+	EffectiveUID int
+}
+
+// procMount is a line of /proc/<pid>/mountinfo
+type procMount struct {
+	Root        string
+	MountPoint  string
+	ReadOnly    bool
+	Propagation string
+	FSType      string
+}
+
+func CheckProcStatus(t Target) (res Result) {
+	res.Name = "Verify that container processes run with the configured restrictions"
+	auditContainerProcs(t, &res, func(c Container, dir string) (findings []string, err error) {
+		content, err := ioutil.ReadFile(filepath.Join(dir, "status"))
+		if err != nil {
+			return nil, err
+		}
+		status := parseProcStatus(content)
+		info := c.Info
+		if info.HostConfig == nil {
+			return
+		}
+		configured := effectiveCapabilities(info)
+		allowed, matched := allowedCapabilities(t.Policy.Capabilities, c)
+		for _, capability := range status.CapEff {
+			if !stringInSlice(capability, configured) {
+				findings = append(findings, capability+" not configured")
+			} else if matched && !stringInSlice(capability, allowed) {
+				findings = append(findings, capability+" not allowed by policy")
+			}
+		}
+		if noNewPrivileges(info.HostConfig.SecurityOpt) && !status.NoNewPrivs {
+			findings = append(findings, "no_new_privs not set")
+		}
+		if mode, _, _ := getSeccompProfile(info); mode != "unconfined" && status.Seccomp == 0 {
+			findings = append(findings, "seccomp not enforced")
+		}
+		if info.Config != nil && isNonRootUser(info.Config.User) && status.EffectiveUID == 0 {
+			findings = append(findings, fmt.Sprintf("runs as root instead of %s", info.Config.User))
+		}
+		return
+	})
+	return
+}
+
+func CheckProcLSMLabel(t Target) (res Result) {
+	res.Name = "Verify that container processes run with the configured LSM label"
+	auditContainerProcs(t, &res, func(c Container, dir string) (findings []string, err error) {
+		label, err := readProcLabel(dir)
+		if err != nil {
+			return nil, err
+		}
+		// AppArmor reports "profile (mode)" and SELinux the bare context
+		current := label
+		if i := strings.LastIndex(label, " ("); i > 0 {
+			current = label[:i]
+		}
+		for _, configured := range []string{c.Info.AppArmorProfile, c.Info.ProcessLabel} {
+			if configured != "" && configured != current {
+				findings = append(findings, fmt.Sprintf("label %s instead of %s", label, configured))
+			}
+		}
+		return
+	})
+	return
+}
+
+func CheckProcMounts(t Target) (res Result) {
+	res.Name = "Verify that container mounts do not expose the host"
+	auditContainerProcs(t, &res, func(c Container, dir string) (findings []string, err error) {
+		content, err := ioutil.ReadFile(filepath.Join(dir, "mountinfo"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range parseMountinfo(content) {
+			if strings.HasPrefix(m.Propagation, "shared") {
+				findings = append(findings, m.MountPoint+" shared propagation")
+			}
+			if m.ReadOnly || stringInSlice(m.FSType, virtualFilesystems) || stringInSlice(m.MountPoint, runtimeMountPoints) {
+				continue
+			}
+			// Denied paths are left to the sensitive_dirs check, as the roots of
+			// named volumes are below Docker's state directory
+			if m.Root == "/" || matchesMountRule(m.Root, t.Policy.Mounts.readOnly()) {
+				findings = append(findings, fmt.Sprintf("%s writable from host %s", m.MountPoint, m.Root))
+			}
+		}
+		return
+	})
+	return
+}
+
+// auditContainerProcs runs describe on the /proc directory of each container's
+// init process and fails the result with the findings. Containers whose
+// process cannot be read are left out.
+func auditContainerProcs(t Target, res *Result, describe func(c Container, dir string) ([]string, error)) {
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	t.Containers.auditContainers(res, func(c Container) ([]string, error) {
+		if c.Info.ContainerJSONBase == nil || c.Info.State == nil || c.Info.State.Pid == 0 {
+			return nil, fmt.Errorf("container %s has no process", c.ID)
+		}
+		return describe(c, filepath.Join(t.BaseDir, "/proc", strconv.Itoa(c.Info.State.Pid)))
+	}, "", "Unable to read container processes from /proc")
+}
+
+func parseProcStatus(content []byte) (status procStatus) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		fields := strings.Fields(kv[1])
+		if len(fields) == 0 {
+			continue
+		}
+		switch kv[0] {
+		case "CapEff":
+			status.CapEff = decodeCapabilities(fields[0])
+		case "NoNewPrivs":
+			status.NoNewPrivs = fields[0] == "1"
+		case "Seccomp":
+			status.Seccomp, _ = strconv.Atoi(fields[0])
+		case "Uid":
+			// Real, effective, saved set and filesystem UIDs
+			if len(fields) > 1 {
+				status.EffectiveUID, _ = strconv.Atoi(fields[1])
+			}
+		}
+	}
+	return
+}
+
+// decodeCapabilities turns a capability bitmask in hex into capability names
+func decodeCapabilities(mask string) (caps []string) {
+	bits, err := strconv.ParseUint(mask, 16, 64)
+	if err != nil {
+		return nil
+	}
+	for i, capability := range allCapabilities {
+		if bits&(1<<uint(i)) != 0 {
+			caps = append(caps, capability)
+		}
+	}
+	return
+}
+
+// readProcLabel returns the LSM label of a process. Kernels stacking LSMs
+// expose AppArmor's under attr/apparmor.
+func readProcLabel(dir string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "attr/apparmor/current"))
+	if err != nil {
+		content, err = ioutil.ReadFile(filepath.Join(dir, "attr/current"))
+	}
+	return strings.TrimSpace(strings.TrimRight(string(content), "\x00")), err
+}
+
+// parseMountinfo parses lines of the form
+// "id parent major:minor root mountpoint options [optional...] - fstype source superoptions"
+func parseMountinfo(content []byte) (mounts []procMount) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 6 || sep < 6 || sep+1 >= len(fields) {
+			continue
+		}
+		m := procMount{
+			Root:       unescapeMountPath(fields[3]),
+			MountPoint: unescapeMountPath(fields[4]),
+			ReadOnly:   strings.HasPrefix(fields[5], "ro"),
+			FSType:     fields[sep+1],
+		}
+		for _, optional := range fields[6:sep] {
+			if strings.HasPrefix(optional, "shared:") || strings.HasPrefix(optional, "master:") {
+				m.Propagation = optional
+				break
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return
+}
+
+// unescapeMountPath decodes the octal escapes the kernel uses for spaces,
+// tabs, newlines and backslashes in mount paths
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// noNewPrivileges tells whether the security options prevent privilege
+// escalation, as "no-new-privileges" or "no-new-privileges=true"
+func noNewPrivileges(opts []string) bool {
+	if stringInSlice("no-new-privileges", opts) {
+		return true
+	}
+	value, ok := getSecurityOpt(opts, "no-new-privileges")
+	return ok && value != "false"
+}
+
+// isNonRootUser tells whether a container's user setting names a user other
+// than root, by name or UID
+func isNonRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name != "" && name != "root" && name != "0"
+}
@@ -0,0 +1,118 @@
+package actuary
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// Docker's default capabilities
+const defaultCapEff = "00000000a80425fb"
+
+// Creates a target whose container's init process has PID 42, with the given
+// files written under BaseDir/proc/42
+func procTestTarget(t *testing.T, hc *container.HostConfig, files map[string]string) *Target {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State:      &types.ContainerState{Running: true, Pid: 42},
+			HostConfig: hc,
+		},
+		Config: &container.Config{User: "app"},
+	})
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTarget.BaseDir = dir
+	for name, content := range files {
+		fpath := filepath.Join(dir, "proc/42", name)
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fpath, []byte(content), 0444); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return testTarget
+}
+
+func procStatusFile(capEff, noNewPrivs, seccomp, uid string) string {
+	return "Name:\tapp\nUid:\t" + uid + "\t" + uid + "\t" + uid + "\t" + uid + "\nCapEff:\t" + capEff +
+		"\nNoNewPrivs:\t" + noNewPrivs + "\nSeccomp:\t" + seccomp + "\n"
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	caps := decodeCapabilities(defaultCapEff)
+	expected := append([]string{}, defaultCapabilities...)
+	sort.Strings(caps)
+	sort.Strings(expected)
+	assert.Equal(t, expected, caps, "Default capability mask decoded incorrectly.")
+}
+
+func TestCheckProcStatusSuccess(t *testing.T) {
+	hc := &container.HostConfig{SecurityOpt: []string{"no-new-privileges:true"}}
+	testTarget := procTestTarget(t, hc, map[string]string{"status": procStatusFile(defaultCapEff, "1", "2", "1000")})
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckProcStatus(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Process matches its configuration, should have passed.")
+}
+
+func TestCheckProcStatusFail(t *testing.T) {
+	hc := &container.HostConfig{SecurityOpt: []string{"no-new-privileges"}}
+	// CAP_SYS_ADMIN is bit 21
+	testTarget := procTestTarget(t, hc, map[string]string{"status": procStatusFile("00000000a82425fb", "0", "0", "0")})
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckProcStatus(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Process differs from its configuration, should not have passed.")
+	for _, finding := range []string{"SYS_ADMIN not configured", "no_new_privs not set", "seccomp not enforced", "runs as root instead of app"} {
+		assert.Contains(t, res.Output, finding)
+	}
+}
+
+func TestCheckProcStatusUnreadable(t *testing.T) {
+	testTarget := procTestTarget(t, &container.HostConfig{}, nil)
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckProcStatus(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No process can be read, should skip.")
+}
+
+func TestCheckProcLSMLabel(t *testing.T) {
+	testTarget := procTestTarget(t, &container.HostConfig{}, map[string]string{"attr/current": "docker-default (enforce)\n"})
+	defer os.RemoveAll(testTarget.BaseDir)
+	testTarget.Containers[0].Info.AppArmorProfile = "docker-default"
+	res := CheckProcLSMLabel(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Process runs with the configured profile, should have passed.")
+
+	testTarget.Containers[0].Info.AppArmorProfile = "hardened"
+	res = CheckProcLSMLabel(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Process runs with another profile, should not have passed.")
+}
+
+func TestCheckProcMounts(t *testing.T) {
+	mountinfo := `600 500 0:50 / / rw,relatime master:1 - overlay overlay rw,lowerdir=/l
+601 600 0:52 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw
+602 600 8:1 /var/lib/docker/containers/abc/hosts /etc/hosts rw,relatime - ext4 /dev/sda1 rw
+603 600 8:1 /var/lib/docker/volumes/data/_data /data rw,relatime - ext4 /dev/sda1 rw
+`
+	testTarget := procTestTarget(t, &container.HostConfig{}, map[string]string{"mountinfo": mountinfo})
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckProcMounts(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "No sensitive host directories are mounted, should have passed.")
+
+	mountinfo += `604 600 8:1 /etc /host\040etc rw,relatime - ext4 /dev/sda1 rw
+605 600 8:1 /usr /host/usr ro,relatime - ext4 /dev/sda1 rw
+606 600 8:1 /srv /srv rw,relatime shared:5 - ext4 /dev/sda1 rw
+`
+	testTarget = procTestTarget(t, &container.HostConfig{}, map[string]string{"mountinfo": mountinfo})
+	defer os.RemoveAll(testTarget.BaseDir)
+	res = CheckProcMounts(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Host /etc is mounted writable, should not have passed.")
+	assert.Contains(t, res.Output, "/host etc writable from host /etc")
+	assert.Contains(t, res.Output, "/srv shared propagation")
+	assert.NotContains(t, res.Output, "/usr")
+}
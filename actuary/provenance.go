@@ -0,0 +1,216 @@
+/*
+Package checks - 4 Container Images and Build File (image provenance)
+An image referenced by tag can change under a running deployment, and an image
+that was built or loaded locally has no registry to vouch for it. These checks
+verify where images come from and that they are the ones that were approved.
+*/
+package actuary
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"strings"
+)
+
+func CheckImageDigestPinning(t Target) (res Result) {
+	res.Name = "Verify that containers reference images by digest"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	pinned := func(c ContainerInfo) bool {
+		if c.Config == nil || c.ContainerJSONBase == nil {
+			return false
+		}
+		// Containers created from an image ID are pinned as well
+		return strings.Contains(c.Config.Image, "@sha256:") || isImageID(c.Config.Image, c.Image)
+	}
+	t.Containers.runCheck(&res, pinned, "Containers referencing images by mutable tag: %s")
+	return
+}
+
+func CheckImageRepoDigests(t Target) (res Result) {
+	res.Name = "Verify that images in use were pulled from a registry"
+	auditImageInspect(t, &res, func(img usedImage, inspect types.ImageInspect) string {
+		if len(inspect.RepoDigests) == 0 {
+			return "no repository digest"
+		}
+		return ""
+	})
+	return
+}
+
+func CheckImageRegistry(t Target) (res Result) {
+	res.Name = "Verify that images come from allowed registries"
+	allowed := t.Policy.Images.AllowedRegistries
+	if len(allowed) == 0 {
+		res.Skip("No allowed registries configured")
+		return
+	}
+	auditImageInspect(t, &res, func(img usedImage, inspect types.ImageInspect) string {
+		registries := getImageRegistries(img, inspect.RepoDigests)
+		if len(registries) == 0 {
+			return "unknown registry"
+		}
+		for _, registry := range registries {
+			if !stringInSlice(registry, allowed) {
+				return fmt.Sprintf("registry %s not allowed", registry)
+			}
+		}
+		return ""
+	})
+	return
+}
+
+func CheckImageDigestAllowlist(t Target) (res Result) {
+	res.Name = "Verify that images in use are on the signed digest allowlist"
+	p := t.Policy.Images
+	if p.DigestAllowlist == "" {
+		res.Skip("No digest allowlist configured")
+		return
+	}
+	if p.DigestAllowlistSignature == "" || p.DigestAllowlistKey == "" {
+		res.Skip("The digest allowlist requires a signature and a public key")
+		return
+	}
+	allowlist, err := loadDigestAllowlist(p)
+	if err != nil {
+		res.Fail(fmt.Sprintf("Digest allowlist could not be verified: %v", err))
+		return
+	}
+	auditImageInspect(t, &res, func(img usedImage, inspect types.ImageInspect) string {
+		if allowlist[img.ID] {
+			return ""
+		}
+		for _, repoDigest := range inspect.RepoDigests {
+			if i := strings.Index(repoDigest, "@"); i >= 0 && allowlist[repoDigest[i+1:]] {
+				return ""
+			}
+		}
+		return "digest not approved"
+	})
+	return
+}
+
+// auditImageInspect inspects every image in use and fails the result with the
+// description returned for each image, naming the containers using it
+func auditImageInspect(t Target, res *Result, describe func(img usedImage, inspect types.ImageInspect) string) {
+	var findings []string
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, img := range getImagesInUse(t) {
+		inspect, _, err := t.Client.ImageInspectWithRaw(context.TODO(), img.ID)
+		if err != nil {
+			continue
+		}
+		if desc := describe(img, inspect); desc != "" {
+			findings = append(findings, fmt.Sprintf("%s used by %s: %s", img.Ref, img.Containers, desc))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail(strings.Join(findings, "; "))
+	}
+}
+
+// getImageRegistries returns the registry an image was referenced from or,
+// when the container was created from an image ID, the registries the image
+// was pulled from
+func getImageRegistries(img usedImage, repoDigests []string) (registries []string) {
+	if !isImageID(img.Ref, img.ID) {
+		if named, err := reference.ParseNormalizedNamed(img.Ref); err == nil {
+			return []string{reference.Domain(named)}
+		}
+	}
+	for _, repoDigest := range repoDigests {
+		named, err := reference.ParseNormalizedNamed(repoDigest)
+		if err == nil && !stringInSlice(reference.Domain(named), registries) {
+			registries = append(registries, reference.Domain(named))
+		}
+	}
+	return
+}
+
+// isImageID tells whether ref is the ID of the image, possibly shortened
+func isImageID(ref, id string) bool {
+	return ref != "" && (ref == id || strings.HasPrefix(id, "sha256:"+ref))
+}
+
+// loadDigestAllowlist verifies the allowlist signature and returns the listed
+// digests. Each line holds a digest, optionally followed by a comment.
+func loadDigestAllowlist(p ImagePolicy) (map[string]bool, error) {
+	content, err := ioutil.ReadFile(p.DigestAllowlist)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ioutil.ReadFile(p.DigestAllowlistSignature)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(p.DigestAllowlistKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(content, sig, keyPEM); err != nil {
+		return nil, err
+	}
+	allowlist := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		allowlist[fields[0]] = true
+	}
+	return allowlist, nil
+}
+
+// verifySignature checks a signature over data made with the private half of
+// a PEM encoded ECDSA, RSA (PKCS #1 v1.5) or Ed25519 public key, using SHA-256
+// for the first two. The signature may be raw or base64 encoded.
+func verifySignature(data, sig, keyPEM []byte) error {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return fmt.Errorf("no PEM data found in public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
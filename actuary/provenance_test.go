@@ -0,0 +1,121 @@
+package actuary
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckImageDigestPinningSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testTarget.Containers = ContainerList{
+		{ID: "Container_id1", Info: ContainerInfo{types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{Image: "sha256:abc"},
+			Config:            &container.Config{Image: "nginx@sha256:def"},
+		}}},
+		{ID: "Container_id2", Info: ContainerInfo{types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{Image: "sha256:abc"},
+			Config:            &container.Config{Image: "sha256:abc"},
+		}}},
+	}
+	res := CheckImageDigestPinning(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Images referenced by digest, should have passed.")
+}
+
+func TestCheckImageDigestPinningFail(t *testing.T) {
+	testTarget, _ := imageTestTarget(t, types.ImageInspect{}, nil)
+	res := CheckImageDigestPinning(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Image referenced by tag, should not have passed.")
+}
+
+func TestCheckImageRepoDigests(t *testing.T) {
+	testTarget, ts := imageTestTarget(t, types.ImageInspect{RepoDigests: []string{"app@sha256:def"}}, nil)
+	res := CheckImageRepoDigests(*testTarget)
+	ts.Close()
+	assert.Equal(t, "PASS", res.Status, "Image was pulled, should have passed.")
+
+	testTarget, ts = imageTestTarget(t, types.ImageInspect{}, nil)
+	res = CheckImageRepoDigests(*testTarget)
+	ts.Close()
+	assert.Equal(t, "WARN", res.Status, "Image was built locally, should not have passed.")
+}
+
+func TestCheckImageRegistry(t *testing.T) {
+	testTarget, ts := imageTestTarget(t, types.ImageInspect{}, nil)
+	defer ts.Close()
+	testTarget.Policy.Images.AllowedRegistries = []string{"docker.io"}
+	res := CheckImageRegistry(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "app:latest comes from docker.io, should have passed.")
+
+	testTarget.Policy.Images.AllowedRegistries = []string{"registry.example.com"}
+	res = CheckImageRegistry(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "docker.io is not allowed, should not have passed.")
+	assert.Equal(t, "app:latest used by [Container_id1]: registry docker.io not allowed", res.Output)
+}
+
+func TestGetImageRegistries(t *testing.T) {
+	img := usedImage{ID: "sha256:0123456789", Ref: "registry.example.com:5000/app:1"}
+	assert.Equal(t, []string{"registry.example.com:5000"}, getImageRegistries(img, nil))
+	img.Ref = "0123"
+	assert.Equal(t, []string{"quay.io"}, getImageRegistries(img, []string{
+		"quay.io/app@sha256:4b8d2e0e0f5b0d1bd1e6b1c5b6c1b7f3a0e7c1d2b3a4f5e6d7c8b9a0f1e2d3c4"}))
+}
+
+// Writes a digest allowlist signed with a fresh ECDSA key and configures the
+// target's policy to use it
+func writeSignedAllowlist(t *testing.T, target *Target, content string) string {
+	dir, err := ioutil.TempDir("", "allowlist")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	digest := sha256.Sum256([]byte(content))
+	sig, _ := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	p := &target.Policy.Images
+	p.DigestAllowlist = filepath.Join(dir, "allowlist")
+	p.DigestAllowlistSignature = filepath.Join(dir, "allowlist.sig")
+	p.DigestAllowlistKey = filepath.Join(dir, "key.pub")
+	ioutil.WriteFile(p.DigestAllowlist, []byte(content), 0644)
+	ioutil.WriteFile(p.DigestAllowlistSignature, []byte(base64.StdEncoding.EncodeToString(sig)), 0644)
+	ioutil.WriteFile(p.DigestAllowlistKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0644)
+	return dir
+}
+
+func TestCheckImageDigestAllowlistSuccess(t *testing.T) {
+	testTarget, ts := imageTestTarget(t, types.ImageInspect{RepoDigests: []string{"app@sha256:def"}}, nil)
+	defer ts.Close()
+	dir := writeSignedAllowlist(t, testTarget, "# approved images\nsha256:def app:1.0\n")
+	defer os.RemoveAll(dir)
+	res := CheckImageDigestAllowlist(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Image digest is approved, should have passed.")
+}
+
+func TestCheckImageDigestAllowlistFail(t *testing.T) {
+	testTarget, ts := imageTestTarget(t, types.ImageInspect{RepoDigests: []string{"app@sha256:def"}}, nil)
+	defer ts.Close()
+	dir := writeSignedAllowlist(t, testTarget, "sha256:012\n")
+	defer os.RemoveAll(dir)
+	res := CheckImageDigestAllowlist(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Image digest is not approved, should not have passed.")
+
+	// Approving the image without re-signing the list must not be accepted
+	ioutil.WriteFile(testTarget.Policy.Images.DigestAllowlist, []byte("sha256:def\n"), 0644)
+	res = CheckImageDigestAllowlist(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Tampered allowlist, should not have passed.")
+	assert.Contains(t, res.Output, "could not be verified")
+}
@@ -0,0 +1,212 @@
+/*
+Package checks - 5 Container Runtime (resource limits)
+The limits of each container are compared with the bounds of the resource
+rules picking it. A rule leaves the resources it does not bound unchecked.
+*/
+package actuary
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Docker's default CFS period, used when only a quota is set
+const defaultCPUPeriod = 100000
+
+func CheckResourcePolicy(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that container resource limits follow the resource policy"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	if len(t.Policy.Resources) == 0 {
+		res.Skip("No resource rules configured")
+		return
+	}
+	for _, rule := range t.Policy.Resources {
+		if err := rule.validate(); err != nil {
+			res.Skip(fmt.Sprintf("Invalid resource rule: %v", err))
+			return
+		}
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		var violations []string
+		for _, rule := range t.Policy.Resources {
+			if rule.matches(c) {
+				violations = append(violations, rule.check(c.Info.HostConfig.Resources)...)
+			}
+		}
+		if len(violations) != 0 {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, strings.Join(violations, ", ")))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers outside the resource policy: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+// validate parses the sizes of a rule
+func (r ResourceRule) validate() error {
+	for _, size := range []string{r.MinMemory, r.MaxMemory, r.MaxMemorySwap} {
+		if size == "" {
+			continue
+		}
+		if _, err := units.RAMInBytes(size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns the limits of a container outside the bounds of the rule
+func (r ResourceRule) check(res container.Resources) (violations []string) {
+	// An actual value of 0 means the resource is not limited
+	bound := func(name string, actual, min, max int64, format func(int64) string) {
+		switch {
+		case min != 0 && actual != 0 && actual < min:
+			violations = append(violations, fmt.Sprintf("%s %s below %s", name, format(actual), format(min)))
+		case max != 0 && (actual == 0 || actual > max):
+			violations = append(violations, fmt.Sprintf("%s %s above %s", name, format(actual), format(max)))
+		}
+	}
+	size := func(s string) int64 {
+		if s == "" {
+			return 0
+		}
+		bytes, _ := units.RAMInBytes(s)
+		return bytes
+	}
+	bound("memory", res.Memory, size(r.MinMemory), size(r.MaxMemory), formatBytes)
+	bound("memory+swap", memorySwap(res), 0, size(r.MaxMemorySwap), formatBytes)
+	millis := func(cpus float64) int64 { return int64(cpus * 1000) }
+	bound("cpus", cpuMillis(res), millis(r.MinCPUs), millis(r.MaxCPUs), formatCPUs)
+	bound("cpuset", int64(cpusetSize(res.CpusetCpus)), 0, int64(r.MaxCpusetCPUs), func(n int64) string {
+		if n == 0 {
+			return "all CPUs"
+		}
+		return strconv.FormatInt(n, 10) + " CPUs"
+	})
+	pids := res.PidsLimit
+	if pids < 0 {
+		pids = 0
+	}
+	bound("pids limit", pids, r.MinPidsLimit, r.MaxPidsLimit, formatCount)
+	// Without a weight, the kernel default applies
+	if res.BlkioWeight != 0 {
+		bound("blkio weight", int64(res.BlkioWeight), int64(r.MinBlkioWeight), int64(r.MaxBlkioWeight), formatCount)
+	}
+	ulimits := make(map[string]int64)
+	for _, ulimit := range res.Ulimits {
+		if ulimit != nil {
+			ulimits[ulimit.Name] = ulimit.Hard
+		}
+	}
+	// Unset ulimits are inherited from the daemon and not known here, and
+	// -1 stands for unlimited
+	for _, name := range sortedKeys(r.MaxUlimits) {
+		max := r.MaxUlimits[name]
+		if actual, ok := ulimits[name]; ok && (actual < 0 || actual > max) {
+			violations = append(violations, fmt.Sprintf("ulimit %s %d above %d", name, actual, max))
+		}
+	}
+	for _, name := range sortedKeys(r.MinUlimits) {
+		min := r.MinUlimits[name]
+		if actual, ok := ulimits[name]; ok && actual >= 0 && actual < min {
+			violations = append(violations, fmt.Sprintf("ulimit %s %d below %d", name, actual, min))
+		}
+	}
+	return
+}
+
+// memorySwap returns the limit of memory and swap together. Without a swap
+// limit, containers may use as much swap as memory.
+func memorySwap(res container.Resources) int64 {
+	switch {
+	case res.MemorySwap < 0:
+		return 0
+	case res.MemorySwap == 0:
+		return 2 * res.Memory
+	}
+	return res.MemorySwap
+}
+
+// cpuMillis returns the CPU limit in thousandths of a CPU, from --cpus or the
+// CFS quota and period
+func cpuMillis(res container.Resources) int64 {
+	if res.NanoCPUs != 0 {
+		return res.NanoCPUs / 1000000
+	}
+	if res.CPUQuota <= 0 {
+		return 0
+	}
+	period := res.CPUPeriod
+	if period == 0 {
+		period = defaultCPUPeriod
+	}
+	return res.CPUQuota * 1000 / period
+}
+
+// cpusetSize counts the CPUs of a cpuset such as "0-3,6", or returns 0 when
+// the container may use every CPU
+func cpusetSize(cpuset string) (n int) {
+	for _, part := range strings.Split(cpuset, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		if bounds[0] == "" {
+			continue
+		}
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		if last >= first {
+			n += last - first + 1
+		}
+	}
+	return
+}
+
+func formatBytes(n int64) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return units.BytesSize(float64(n))
+}
+
+func formatCPUs(millis int64) string {
+	if millis == 0 {
+		return "unlimited"
+	}
+	return strconv.FormatFloat(float64(millis)/1000, 'f', -1, 64)
+}
+
+func formatCount(n int64) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func sortedKeys(m map[string]int64) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
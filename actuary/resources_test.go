@@ -0,0 +1,69 @@
+package actuary
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCpusetSize(t *testing.T) {
+	assert.Equal(t, 0, cpusetSize(""), "Empty cpuset means every CPU")
+	assert.Equal(t, 5, cpusetSize("0-3,6"), "Wrong size for 0-3,6")
+	assert.Equal(t, 2, cpusetSize("1, 2"), "Wrong size for 1, 2")
+}
+
+func TestCheckResourcePolicySkip(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{Resources: container.Resources{}}},
+	})
+	res := CheckResourcePolicy(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No resource rules, should skip.")
+
+	testTarget.Policy.Resources = []ResourceRule{{MaxMemory: "lots"}}
+	res = CheckResourcePolicy(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "Invalid memory size, should skip.")
+}
+
+func TestCheckResourcePolicySuccess(t *testing.T) {
+	resources := container.Resources{
+		Memory:     512 * 1024 * 1024,
+		MemorySwap: 512 * 1024 * 1024,
+		CPUQuota:   50000,
+		CpusetCpus: "0-1",
+		PidsLimit:  200,
+		Ulimits:    []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+	}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{Resources: resources}},
+	})
+	testTarget.Policy.Resources = []ResourceRule{{
+		MinMemory: "64m", MaxMemory: "1g", MaxMemorySwap: "1g", MaxCPUs: 1, MaxCpusetCPUs: 2,
+		MinPidsLimit: 50, MaxPidsLimit: 500, MaxUlimits: map[string]int64{"nofile": 65536},
+	}}
+	res := CheckResourcePolicy(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Limits within the policy, should have passed: %s", res.Output)
+}
+
+func TestCheckResourcePolicyFail(t *testing.T) {
+	resources := container.Resources{
+		Memory:    2 * 1024 * 1024 * 1024,
+		NanoCPUs:  4000000000,
+		PidsLimit: -1,
+		Ulimits:   []*units.Ulimit{{Name: "nofile", Soft: 1024, Hard: -1}},
+	}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{Resources: resources}},
+		Config:            &container.Config{Labels: map[string]string{"tier": "web"}},
+	})
+	testTarget.Policy.Resources = []ResourceRule{
+		{ContainerSelector: ContainerSelector{Label: "tier=web"}, MaxMemory: "1g", MaxMemorySwap: "2g", MaxCPUs: 2},
+		{MaxPidsLimit: 500, MaxUlimits: map[string]int64{"nofile": 65536}},
+		{ContainerSelector: ContainerSelector{Label: "tier=db"}, MinMemory: "4g"},
+	}
+	res := CheckResourcePolicy(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Limits outside the policy, should not have passed.")
+	assert.Equal(t, "Containers outside the resource policy: Container_id1: memory 2GiB above 1GiB, "+
+		"memory+swap 4GiB above 2GiB, cpus 4 above 2, pids limit unlimited above 500, ulimit nofile -1 above 65536", res.Output)
+}
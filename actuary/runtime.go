@@ -7,61 +7,12 @@ other containers on the host. Verifying container runtime is thus very important
 package actuary
 
 import (
+	"encoding/json"
 	"fmt"
 	"golang.org/x/net/context"
 	"strconv"
-	"strings"
 )
 
-func CheckAppArmor(t Target) (res Result) {
-	res.Name = "5.1 Verify AppArmor Profile, if applicable"
-	if !t.Containers.Running() {
-		res.Skip("No running containers")
-		return
-	}
-	apparmor := func(c ContainerInfo) bool {
-		if c.AppArmorProfile == "" {
-			return false
-		}
-		return true
-	}
-	t.Containers.runCheck(&res, apparmor, "Containers with no AppArmor profile: %s")
-	return
-}
-
-func CheckSELinux(t Target) (res Result) {
-	res.Name = "5.1 Verify AppArmor Profile, if applicable"
-	if !t.Containers.Running() {
-		res.Skip("No running containers")
-		return
-	}
-	selinux := func(c ContainerInfo) bool {
-		if c.HostConfig.SecurityOpt == nil {
-			return false
-		}
-		return true
-	}
-	t.Containers.runCheck(&res, selinux, "Containers with no SELinux options: %s")
-	return
-}
-
-func CheckKernelCapabilities(t Target) (res Result) {
-	res.Name = "5.3 Restrict Linux Kernel Capabilities within containers"
-	if !t.Containers.Running() {
-		res.Skip("No running containers")
-		return
-	}
-
-	kernelCap := func(c ContainerInfo) bool {
-		if c.HostConfig.CapAdd != nil {
-			return false
-		}
-		return true
-	}
-	t.Containers.runCheck(&res, kernelCap, "Containers running with added capabilities: %s")
-	return
-}
-
 func CheckPrivContainers(t Target) (res Result) {
 	res.Name = "5.4 Do not use privileged containers"
 	if !t.Containers.Running() {
@@ -79,58 +30,6 @@ func CheckPrivContainers(t Target) (res Result) {
 	return
 }
 
-func CheckSensitiveDirs(t Target) (res Result) {
-	res.Name = "5.5 Do not mount sensitive host system directories on containers "
-	if !t.Containers.Running() {
-		res.Skip("No running containers")
-		return
-	}
-
-	sensitiveDirs := func(c ContainerInfo) bool {
-		mounts := c.Mounts
-		dirList := []string{"/dev", "/etc", "/lib", "/proc", "/sys", "/usr"}
-		for _, mount := range mounts {
-			for _, dir := range dirList {
-				if strings.HasPrefix(mount.Source, dir) && mount.RW == true {
-					return false
-				}
-			}
-		}
-		return true
-	}
-	t.Containers.runCheck(&res, sensitiveDirs, "Sensitive directories mounted on containers: %s")
-	return
-}
-
-func CheckSSHRunning(t Target) (res Result) {
-	var badContainers []string
-	res.Name = "5.6 Do not run ssh within containers"
-	if !t.Containers.Running() {
-		res.Skip("No running containers")
-		return
-	}
-	for _, container := range t.Containers {
-		procs, err := t.Client.ContainerTop(context.TODO(), container.ID, []string{})
-		if err != nil {
-		}
-		// Proc fields are [UID PID PPID C STIME TTY TIME CMD]
-		for _, proc := range procs.Processes {
-			procname := proc[3]
-			if strings.Contains(procname, "ssh") {
-				badContainers = append(badContainers, container.ID)
-			}
-		}
-	}
-	if len(badContainers) == 0 {
-		res.Pass()
-	} else {
-		output := fmt.Sprintf("Containers running SSH service: %s",
-			badContainers)
-		res.Fail(output)
-	}
-	return
-}
-
 func CheckPrivilegedPorts(t Target) (res Result) {
 	res.Name = "5.7 Do not map privileged ports within containers"
 	if !t.Containers.Running() {
@@ -215,14 +114,14 @@ func CheckCPUShares(t Target) (res Result) {
 		res.Skip("No running containers")
 		return
 	}
+	// 1024 is the default weight, but setting it explicitly is a choice too
 	cpuShares := func(c ContainerInfo) bool {
-		shares := c.HostConfig.CPUShares
-		if shares == 0 || shares == 1024 {
+		if c.HostConfig.CPUShares == 0 {
 			return false
 		}
 		return true
 	}
-	t.Containers.runCheck(&res, cpuShares, "Containers with CPU sharing disabled: %s")
+	t.Containers.runCheck(&res, cpuShares, "Containers with no CPU shares set: %s")
 	return
 }
 
@@ -374,23 +273,6 @@ func CheckUTSnamespace(t Target) (res Result) {
 	return
 }
 
-func CheckSeccompProfile(t Target) (res Result) {
-	res.Name = "5.21 Do not disable default seccomp profile"
-	if !t.Containers.Running() {
-		res.Skip("No running containers")
-		return
-	}
-	secComp := func(c ContainerInfo) bool {
-		seccomp := c.HostConfig.SecurityOpt
-		if len(seccomp) == 1 && seccomp[0] == "seccomp:unconfined" {
-			return false
-		}
-		return true
-	}
-	t.Containers.runCheck(&res, secComp, "Containers running with seccomp disabled: %s")
-	return
-}
-
 func CheckCgroupUsage(t Target) (res Result) {
 	res.Name = "5.24 Confirm cgroup usage"
 	if !t.Containers.Running() {
@@ -423,3 +305,134 @@ func CheckAdditionalPrivs(t Target) (res Result) {
 	t.Containers.runCheck(&res, privs, "Containers unrestricted from acquiring additional privileges: %s")
 	return
 }
+
+func CheckContainerHealth(t Target) (res Result) {
+	res.Name = "5.26 Check container health at runtime"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	// Containers without a health check report no status at all. Unhealthy
+	// containers are left to CheckUnhealthyContainers.
+	health := func(c ContainerInfo) bool {
+		if c.ContainerJSONBase == nil || c.State == nil || c.State.Health == nil {
+			return false
+		}
+		return true
+	}
+	t.Containers.runCheck(&res, health, "Containers without a health check: %s")
+	return
+}
+
+func CheckPidsLimit(t Target) (res Result) {
+	res.Name = "5.28 Use PIDs cgroup limit"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	pidsLimit := func(c ContainerInfo) bool {
+		if c.HostConfig.PidsLimit <= 0 {
+			return false
+		}
+		return true
+	}
+	t.Containers.runCheck(&res, pidsLimit, "Containers with no PIDs limit: %s")
+	return
+}
+
+func CheckDefaultBridge(t Target) (res Result) {
+	res.Name = "5.29 Do not use Docker's default bridge docker0"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	bridge := func(c ContainerInfo) bool {
+		if c.HostConfig.NetworkMode == "default" || c.HostConfig.NetworkMode == "bridge" {
+			return false
+		}
+		if c.NetworkSettings != nil {
+			if _, ok := c.NetworkSettings.Networks["bridge"]; ok {
+				return false
+			}
+		}
+		return true
+	}
+	t.Containers.runCheck(&res, bridge, "Containers attached to the default bridge: %s")
+	return
+}
+
+func CheckUsernsMode(t Target) (res Result) {
+	res.Name = "5.30 Do not share the host's user namespaces"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	userns := func(c ContainerInfo) bool {
+		if c.HostConfig.UsernsMode.IsHost() {
+			return false
+		}
+		return true
+	}
+	t.Containers.runCheck(&res, userns, "Containers sharing host's user namespace: %s")
+	return
+}
+
+func CheckDockerSockMount(t Target) (res Result) {
+	res.Name = "5.31 Do not mount the Docker socket inside any containers"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	// Other runtime sockets are reported by CheckSensitiveDirs
+	sockMount := func(c ContainerInfo) bool {
+		for _, m := range c.Mounts {
+			if runtimeSocket(getMountHostPath(t, m)) == "docker.sock" {
+				return false
+			}
+		}
+		return true
+	}
+	t.Containers.runCheck(&res, sockMount, "Containers with the Docker socket mounted: %s")
+	return
+}
+
+func CheckCgroupNamespace(t Target) (res Result) {
+	var badContainers []string
+	res.Name = "Do not share the host's cgroup namespace"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	reported := false
+	for _, container := range t.Containers {
+		// CgroupnsMode is newer than the API types this client uses
+		_, raw, err := t.Client.ContainerInspectWithRaw(context.TODO(), container.ID, false)
+		if err != nil {
+			continue
+		}
+		var inspect struct {
+			HostConfig struct {
+				CgroupnsMode *string
+			}
+		}
+		if json.Unmarshal(raw, &inspect) != nil || inspect.HostConfig.CgroupnsMode == nil {
+			continue
+		}
+		reported = true
+		if *inspect.HostConfig.CgroupnsMode == "host" {
+			badContainers = append(badContainers, container.ID)
+		}
+	}
+	if !reported {
+		res.Skip("Docker daemon does not report the cgroup namespace mode")
+		return
+	}
+	if len(badContainers) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Containers sharing host's cgroup namespace: %s",
+			badContainers)
+		res.Fail(output)
+	}
+	return
+}
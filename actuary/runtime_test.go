@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -29,6 +31,7 @@ func TestCheckAppArmorSuccess(t *testing.T) {
 	if err != nil {
 		t.Errorf("Could not create testTarget")
 	}
+	testTarget.Info.SecurityOptions = []string{"name=apparmor"}
 	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{AppArmorProfile: "yes"}, nil, nil, nil}}
 	f := func(c Container) Container {
 		c.Info = info
@@ -42,6 +45,7 @@ func TestCheckAppArmorFail(t *testing.T) {
 	if err != nil {
 		t.Errorf("Could not create testTarget")
 	}
+	testTarget.Info.SecurityOptions = []string{"name=apparmor"}
 	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{AppArmorProfile: ""}, nil, nil, nil}}
 	f := func(c Container) Container {
 		c.Info = info
@@ -55,12 +59,13 @@ func TestCheckSELinuxSuccess(t *testing.T) {
 	if err != nil {
 		t.Errorf("Could not create testTarget")
 	}
-	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{SecurityOpt: []string{"SELinux", "Array"}}}, nil, nil, nil}}
+	testTarget.Info.SecurityOptions = []string{"name=selinux"}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{ProcessLabel: "system_u:system_r:container_t:s0:c1,c2", MountLabel: "system_u:object_r:container_file_t:s0:c1,c2", HostConfig: &container.HostConfig{}}, nil, nil, nil}}
 	f := func(c Container) Container {
 		c.Info = info
 		return c
 	}
-	containerTestsHelper(t, *testTarget, CheckSELinux, f, "All containers have SELinux options, should have passed.", "PASS")
+	containerTestsHelper(t, *testTarget, CheckSELinux, f, "All containers have SELinux labels, should have passed.", "PASS")
 }
 
 func TestCheckSELinuxFail(t *testing.T) {
@@ -68,6 +73,7 @@ func TestCheckSELinuxFail(t *testing.T) {
 	if err != nil {
 		t.Errorf("Could not create testTarget")
 	}
+	testTarget.Info.SecurityOptions = []string{"name=selinux"}
 	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{SecurityOpt: nil}}, nil, nil, nil}}
 	f := func(c Container) Container {
 		c.Info = info
@@ -189,7 +195,7 @@ func TestCheckSSHRunningFail(t *testing.T) {
 			{"root", "13735", "13642", "0", "17:06", "pts/0", "00:00:00", "sleep 10"}},
 		Titles: []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"},
 	}
-	processList.Processes[0][3] = "ssh"
+	processList.Processes[1][7] = "/usr/sbin/sshd -D"
 
 	temp := testTarget.Containers
 	testTarget.Containers = ContainerList{testTarget.Containers[0]}
@@ -327,6 +333,20 @@ func TestCheckCPUSharesFail(t *testing.T) {
 	containerTestsHelper(t, *testTarget, CheckCPUShares, f, "Containers with CPU sharing disabled, should not have passed.", "WARN")
 }
 
+func TestCheckCPUSharesDefaultWeight(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	resource := container.Resources{CPUShares: 1024}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{Resources: resource}}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckCPUShares, f, "CPU shares explicitly set to 1024, should have passed.", "PASS")
+}
+
 func TestCheckReadonlyRootSuccess(t *testing.T) {
 	testTarget, err := NewTestTarget([]string{""})
 	if err != nil {
@@ -614,3 +634,220 @@ func TestCheckAdditionalPrivsFail(t *testing.T) {
 	}
 	containerTestsHelper(t, *testTarget, CheckAdditionalPrivs, f, "Containers unrestricted from acquiring additional privileges, should not have passed.", "WARN")
 }
+
+func TestCheckContainerHealthSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{State: &types.ContainerState{Health: &types.Health{Status: "healthy"}}}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckContainerHealth, f, "Containers have a health check, should have passed.", "PASS")
+}
+
+func TestCheckContainerHealthFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{State: &types.ContainerState{}}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckContainerHealth, f, "Containers without a health check, should not have passed.", "WARN")
+}
+
+func TestCheckContainerHealthNotHealthy(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	for _, status := range []string{types.Starting, types.Unhealthy} {
+		state := &types.ContainerState{Health: &types.Health{Status: status}}
+		info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{State: state}, nil, nil, nil}}
+		f := func(c Container) Container {
+			c.Info = info
+			return c
+		}
+		containerTestsHelper(t, *testTarget, CheckContainerHealth, f, "Containers with a health check, should have passed.", "PASS")
+	}
+}
+
+func TestCheckPidsLimitSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	hostConfig := &container.HostConfig{}
+	hostConfig.PidsLimit = 100
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: hostConfig}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckPidsLimit, f, "Containers have a PIDs limit, should have passed.", "PASS")
+}
+
+func TestCheckPidsLimitFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{}}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckPidsLimit, f, "Containers with no PIDs limit, should not have passed.", "WARN")
+}
+
+func TestCheckDefaultBridgeSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	networks := &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{"app": {}}}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{NetworkMode: "app"}}, nil, nil, networks}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckDefaultBridge, f, "Containers on a user-defined network, should have passed.", "PASS")
+}
+
+func TestCheckDefaultBridgeFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	networks := &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{"bridge": {}}}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{NetworkMode: "default"}}, nil, nil, networks}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckDefaultBridge, f, "Containers on the default bridge, should not have passed.", "WARN")
+}
+
+func TestCheckUsernsModeSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{UsernsMode: ""}}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckUsernsMode, f, "Containers not sharing host's user namespace, should have passed.", "PASS")
+}
+
+func TestCheckUsernsModeFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: &container.HostConfig{UsernsMode: "host"}}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckUsernsMode, f, "Containers sharing host's user namespace, should not have passed.", "WARN")
+}
+
+func TestCheckDockerSockMountSuccess(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	// Other runtime sockets are left to CheckSensitiveDirs
+	info := ContainerInfo{types.ContainerJSON{nil, []types.MountPoint{{Source: "/srv/data"}, {Source: "/run/containerd/containerd.sock"}}, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckDockerSockMount, f, "Docker socket not mounted, should have passed.", "PASS")
+}
+
+func TestCheckDockerSockMountFail(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	info := ContainerInfo{types.ContainerJSON{nil, []types.MountPoint{{Source: "/var/run/docker.sock"}}, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckDockerSockMount, f, "Docker socket mounted, should not have passed.", "WARN")
+}
+
+func TestCheckCgroupNamespace(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	call := "/containers/" + testTarget.Containers[0].ID + "/json"
+	ts := testTarget.testServer(t, callPairing{call, []byte(`{"Id": "Container_id1", "HostConfig": {"CgroupnsMode": "private"}}`)})
+	res := CheckCgroupNamespace(*testTarget)
+	ts.Close()
+	assert.Equal(t, "PASS", res.Status, "Container with a private cgroup namespace, should have passed.")
+
+	ts = testTarget.testServer(t, callPairing{call, []byte(`{"Id": "Container_id1", "HostConfig": {"CgroupnsMode": "host"}}`)})
+	res = CheckCgroupNamespace(*testTarget)
+	ts.Close()
+	assert.Equal(t, "WARN", res.Status, "Container sharing host's cgroup namespace, should not have passed.")
+
+	ts = testTarget.testServer(t, callPairing{call, []byte(`{"Id": "Container_id1", "HostConfig": {}}`)})
+	res = CheckCgroupNamespace(*testTarget)
+	ts.Close()
+	assert.Equal(t, "SKIP", res.Status, "Daemon without cgroup namespace support, should have skipped.")
+}
+
+func TestCheckKernelCapabilitiesDropAll(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	hc := &container.HostConfig{CapAdd: []string{"CAP_NET_BIND_SERVICE"}, CapDrop: []string{"all"}}
+	info := ContainerInfo{types.ContainerJSON{&types.ContainerJSONBase{HostConfig: hc}, nil, nil, nil}}
+	f := func(c Container) Container {
+		c.Info = info
+		return c
+	}
+	containerTestsHelper(t, *testTarget, CheckKernelCapabilities, f, "Containers only adding back default capabilities, should have passed.", "PASS")
+}
+
+func TestCheckKernelCapabilitiesPrivileged(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{Privileged: true}},
+	})
+	res := CheckKernelCapabilities(*testTarget)
+	if res.Status != "WARN" || !strings.Contains(res.Output, "SYS_ADMIN (high)") {
+		t.Errorf("Privileged container should have been reported with SYS_ADMIN, got %s: %s", res.Status, res.Output)
+	}
+}
+
+func TestCheckKernelCapabilitiesPolicy(t *testing.T) {
+	base := &types.ContainerJSONBase{Name: "/vpn-1", HostConfig: &container.HostConfig{CapAdd: []string{"NET_ADMIN"}, CapDrop: []string{"NET_RAW"}}}
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: base, Config: &container.Config{Image: "vpn:1"}})
+	testTarget.Policy.Capabilities = []CapabilityRule{{ContainerSelector{Name: "vpn-*"}, append([]string{"NET_ADMIN"}, defaultCapabilities...)}}
+	if res := CheckKernelCapabilities(*testTarget); res.Status != "PASS" {
+		t.Errorf("NET_ADMIN is allowed for vpn containers, should have passed: %s", res.Output)
+	}
+
+	testTarget.Policy.Capabilities = []CapabilityRule{{ContainerSelector{Image: "vpn:*"}, []string{"CHOWN"}}}
+	res := CheckKernelCapabilities(*testTarget)
+	if res.Status != "WARN" || !strings.Contains(res.Output, "NET_ADMIN (medium)") || strings.Contains(res.Output, "NET_RAW") {
+		t.Errorf("NET_ADMIN is not allowed and NET_RAW was dropped, got %s: %s", res.Status, res.Output)
+	}
+
+	testTarget.Policy.Capabilities = []CapabilityRule{{ContainerSelector{Label: "team=net"}, []string{"ALL"}}}
+	if res := CheckKernelCapabilities(*testTarget); res.Status != "WARN" {
+		t.Errorf("Rule does not match the container, NET_ADMIN is not a default capability: %s", res.Output)
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Package checks - 4 Container Images and Build File (software bill of materials)
+Lists the OS packages and language packages found in the images used by the
+target's containers, as SPDX or CycloneDX JSON documents.
+*/
+package actuary
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImageSBOM is the bill of materials of an image used by the target's containers
+type ImageSBOM struct {
+	ImageID  string
+	Ref      string
+	Document []byte
+}
+
+// sbomPackage is a package listed in a bill of materials
+type sbomPackage struct {
+	Name    string
+	Version string
+	PURL    string
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXComponent struct {
+	Type    string `json:"type"`
+	BOMRef  string `json:"bom-ref"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+// GenerateSBOMs builds a bill of materials in the given format, "spdx" or
+// "cyclonedx", for each image used by the target's containers
+func GenerateSBOMs(t Target, format string) (sboms []ImageSBOM, err error) {
+	format = strings.ToLower(format)
+	if format != "spdx" && format != "cyclonedx" {
+		return nil, fmt.Errorf("unsupported SBOM format: %s", format)
+	}
+	now := time.Now().UTC()
+	for _, img := range getImagesInUse(t) {
+		fs, err := scanImage(t, img.ID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image %s: %v", img.Ref, err)
+		}
+		pkgs := getSBOMPackages(fs)
+		var doc interface{}
+		if format == "spdx" {
+			doc = newSPDXDocument(img, pkgs, now)
+		} else {
+			doc = newCycloneDXDocument(img, pkgs, now)
+		}
+		content, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		sboms = append(sboms, ImageSBOM{ImageID: img.ID, Ref: img.Ref, Document: content})
+	}
+	return
+}
+
+func newSPDXDocument(img usedImage, pkgs []sbomPackage, now time.Time) spdxDocument {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              img.Ref,
+		DocumentNamespace: fmt.Sprintf("https://github.com/diogomonica/actuary/sbom/%s-%d", img.ID, now.Unix()),
+		CreationInfo: spdxCreationInfo{
+			Created:  now.Format(time.RFC3339),
+			Creators: []string{"Tool: actuary"},
+		},
+	}
+	doc.Packages = append(doc.Packages, spdxPackage{
+		Name:             img.Ref,
+		SPDXID:           "SPDXRef-Image",
+		VersionInfo:      img.ID,
+		DownloadLocation: "NOASSERTION",
+	})
+	doc.Relationships = append(doc.Relationships, spdxRelationship{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Image"})
+	for i, pkg := range pkgs {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			Name:             pkg.Name,
+			SPDXID:           id,
+			VersionInfo:      pkg.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs:     []spdxExternalRef{{"PACKAGE-MANAGER", "purl", pkg.PURL}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{"SPDXRef-Image", "CONTAINS", id})
+	}
+	return doc
+}
+
+func newCycloneDXDocument(img usedImage, pkgs []sbomPackage, now time.Time) cycloneDXDocument {
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: now.Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: "actuary"}},
+			Component: cycloneDXComponent{Type: "container", BOMRef: img.ID, Name: img.Ref, Version: img.ID},
+		},
+		Components: []cycloneDXComponent{},
+	}
+	for _, pkg := range pkgs {
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  pkg.PURL,
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			PURL:    pkg.PURL,
+		})
+	}
+	return doc
+}
+
+// getSBOMPackages returns the OS and language packages found in an image,
+// sorted by package URL
+func getSBOMPackages(fs imageFS) (pkgs []sbomPackage) {
+	distro := getImageDistro(fs)
+	qualifier := ""
+	if distro.ID != "" {
+		qualifier = "?distro=" + url.QueryEscape(distro.ID+"-"+distro.Version)
+	}
+	osType := "deb"
+	if distro.ID == "alpine" {
+		osType = "apk"
+	}
+	for _, pkg := range getImagePackages(fs) {
+		purl := fmt.Sprintf("pkg:%s/%s/%s@%s%s", osType, url.QueryEscape(distro.ID),
+			url.QueryEscape(pkg.Name), url.QueryEscape(pkg.Version), qualifier)
+		pkgs = append(pkgs, sbomPackage{pkg.Name, pkg.Version, purl})
+	}
+	pkgs = append(pkgs, getLanguagePackages(fs)...)
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].PURL < pkgs[j].PURL })
+	return
+}
+
+// isLanguageManifest tells whether a file describes an installed npm or
+// Python package
+func isLanguageManifest(name string) bool {
+	return isNpmManifest(name) || strings.HasSuffix(name, ".dist-info/METADATA") ||
+		strings.HasSuffix(name, ".egg-info/PKG-INFO")
+}
+
+// Only the manifests of installed packages count, not those of test fixtures
+// shipped inside them: node_modules/<name>/package.json or
+// node_modules/@<scope>/<name>/package.json
+func isNpmManifest(name string) bool {
+	if path.Base(name) != "package.json" {
+		return false
+	}
+	parent := path.Dir(path.Dir(name))
+	if strings.HasPrefix(path.Base(parent), "@") {
+		parent = path.Dir(parent)
+	}
+	return path.Base(parent) == "node_modules"
+}
+
+func getLanguagePackages(fs imageFS) (pkgs []sbomPackage) {
+	seen := make(map[string]bool)
+	for _, name := range fs.paths() {
+		var pkg sbomPackage
+		switch {
+		case isNpmManifest(name):
+			var manifest struct {
+				Name    string
+				Version string
+			}
+			if json.Unmarshal(fs[name].Content, &manifest) != nil {
+				continue
+			}
+			pkg = sbomPackage{Name: manifest.Name, Version: manifest.Version}
+			namespace := ""
+			if strings.HasPrefix(pkg.Name, "@") {
+				parts := strings.SplitN(pkg.Name, "/", 2)
+				if len(parts) == 2 {
+					namespace = url.QueryEscape(parts[0]) + "/"
+					pkg.PURL = fmt.Sprintf("pkg:npm/%s%s@%s", namespace,
+						url.QueryEscape(parts[1]), url.QueryEscape(pkg.Version))
+				}
+			} else {
+				pkg.PURL = fmt.Sprintf("pkg:npm/%s@%s", url.QueryEscape(pkg.Name), url.QueryEscape(pkg.Version))
+			}
+		case isLanguageManifest(name):
+			pkg = parsePythonMetadata(fs[name].Content)
+			normalized := strings.Replace(strings.ToLower(pkg.Name), "_", "-", -1)
+			pkg.PURL = fmt.Sprintf("pkg:pypi/%s@%s", url.QueryEscape(normalized), url.QueryEscape(pkg.Version))
+		case path.Base(path.Dir(name)) == "specifications" && path.Ext(name) == ".gemspec":
+			// Installed gems are named <name>-<version>.gemspec
+			base := strings.TrimSuffix(path.Base(name), ".gemspec")
+			if i := strings.LastIndex(base, "-"); i > 0 {
+				pkg = sbomPackage{Name: base[:i], Version: base[i+1:]}
+				pkg.PURL = fmt.Sprintf("pkg:gem/%s@%s", url.QueryEscape(pkg.Name), url.QueryEscape(pkg.Version))
+			}
+		default:
+			continue
+		}
+		if pkg.Name == "" || pkg.Version == "" || pkg.PURL == "" || seen[pkg.PURL] {
+			continue
+		}
+		seen[pkg.PURL] = true
+		pkgs = append(pkgs, pkg)
+	}
+	return
+}
+
+// parsePythonMetadata reads the name and version from the headers of a
+// METADATA or PKG-INFO file
+func parsePythonMetadata(content []byte) (pkg sbomPackage) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Name:") {
+			pkg.Name = strings.TrimSpace(strings.TrimPrefix(line, "Name:"))
+		} else if strings.HasPrefix(line, "Version:") {
+			pkg.Version = strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,71 @@
+package actuary
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func sbomTestTarget(t *testing.T, id string) (*Target, func()) {
+	archive := buildImageArchive(t, buildTar(t, []tarEntry{
+		{"etc/os-release", 0644, "ID=debian\nVERSION_ID=\"11\"\n"},
+		{"var/lib/dpkg/status", 0644, "Package: zlib1g\nVersion: 1:1.2.11.dfsg-2\n"},
+		{"app/node_modules/express/package.json", 0644, `{"name": "express", "version": "4.18.2"}`},
+		{"app/node_modules/@babel/core/package.json", 0644, `{"name": "@babel/core", "version": "7.22.0"}`},
+		{"app/node_modules/express/test/fixture/package.json", 0644, `{"name": "fixture", "version": "0.0.0"}`},
+		{"usr/lib/python3/site-packages/PyYAML-6.0.dist-info/METADATA", 0644, "Metadata-Version: 2.1\nName: PyYAML\nVersion: 6.0\n\nName: not a header\n"},
+		{"usr/lib/ruby/gems/3.0.0/specifications/rack-test-2.1.0.gemspec", 0644, ""},
+	}))
+	testTarget := imageFSTestTarget(t, id)
+	ts := testTarget.testServer(t, callPairing{"/images/get", archive})
+	return testTarget, ts.Close
+}
+
+var expectedPURLs = []string{
+	"pkg:deb/debian/zlib1g@1%3A1.2.11.dfsg-2?distro=debian-11",
+	"pkg:gem/rack-test@2.1.0",
+	"pkg:npm/%40babel/core@7.22.0",
+	"pkg:npm/express@4.18.2",
+	"pkg:pypi/pyyaml@6.0",
+}
+
+func TestGenerateSBOMsSPDX(t *testing.T) {
+	testTarget, cleanup := sbomTestTarget(t, "sha256:sbom")
+	defer cleanup()
+	sboms, err := GenerateSBOMs(*testTarget, "spdx")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sboms))
+	var doc spdxDocument
+	assert.Nil(t, json.Unmarshal(sboms[0].Document, &doc))
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "app:latest", doc.Name)
+	var purls []string
+	for _, pkg := range doc.Packages[1:] {
+		purls = append(purls, pkg.ExternalRefs[0].ReferenceLocator)
+	}
+	assert.Equal(t, expectedPURLs, purls)
+	assert.Equal(t, len(doc.Packages), len(doc.Relationships), "Each package should be related to the image.")
+}
+
+func TestGenerateSBOMsCycloneDX(t *testing.T) {
+	testTarget, cleanup := sbomTestTarget(t, "sha256:sbom")
+	defer cleanup()
+	sboms, err := GenerateSBOMs(*testTarget, "cyclonedx")
+	assert.Nil(t, err)
+	var doc cycloneDXDocument
+	assert.Nil(t, json.Unmarshal(sboms[0].Document, &doc))
+	assert.Equal(t, "CycloneDX", doc.BOMFormat)
+	assert.Equal(t, "sha256:sbom", doc.Metadata.Component.Version)
+	var purls []string
+	for _, c := range doc.Components {
+		purls = append(purls, c.PURL)
+	}
+	assert.Equal(t, expectedPURLs, purls)
+}
+
+func TestGenerateSBOMsInvalidFormat(t *testing.T) {
+	testTarget, cleanup := sbomTestTarget(t, "sha256:sbom")
+	defer cleanup()
+	_, err := GenerateSBOMs(*testTarget, "swid")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,232 @@
+/*
+Package checks - 5 Container Runtime (seccomp profiles)
+A custom seccomp profile is passed to the daemon as JSON in the container's
+security options, so its content can be checked: a profile may be in place and
+still allow the syscalls the default profile exists to deny.
+*/
+package actuary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Syscalls the Docker default profile denies to containers without extra
+// capabilities, which let a process manipulate the kernel or other namespaces
+var dangerousSyscalls = []string{"acct", "add_key", "bpf", "clock_settime", "delete_module",
+	"finit_module", "init_module", "kexec_file_load", "kexec_load", "keyctl", "mount",
+	"name_to_handle_at", "open_by_handle_at", "perf_event_open", "pivot_root", "ptrace",
+	"reboot", "request_key", "setns", "swapoff", "swapon", "umount", "umount2", "unshare",
+	"userfaultfd"}
+
+// seccompProfile holds the parts of a Docker seccomp profile that decide
+// which syscalls are allowed
+type seccompProfile struct {
+	DefaultAction string
+	Syscalls      []seccompRule
+}
+
+type seccompRule struct {
+	// Name is used by profiles older than Docker 17.04
+	Name     string
+	Names    []string
+	Action   string
+	Args     []json.RawMessage
+	Includes seccompFilter
+	Excludes seccompFilter
+}
+
+// seccompFilter restricts a rule to containers holding some capabilities
+type seccompFilter struct {
+	Caps []string
+}
+
+func CheckSeccompProfile(t Target) (res Result) {
+	var findings []string
+	res.Name = "5.21 Do not disable default seccomp profile"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		mode, profile, err := getSeccompProfile(c.Info)
+		var desc string
+		switch {
+		case err != nil:
+			desc = fmt.Sprintf("invalid profile (%v)", err)
+		case mode == "unconfined":
+			desc = "seccomp disabled"
+			if c.Info.HostConfig.Privileged {
+				desc += " (privileged)"
+			}
+		case mode == "custom" && isAllowAction(profile.DefaultAction):
+			desc = "default action " + profile.DefaultAction
+		case mode == "custom":
+			var allowed []string
+			caps := effectiveCapabilities(c.Info)
+			for _, name := range dangerousSyscalls {
+				if profile.allows(name, caps) {
+					allowed = append(allowed, name)
+				}
+			}
+			if len(allowed) != 0 {
+				desc = "allows " + summarize(allowed, 10)
+			}
+		}
+		if desc != "" {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, desc))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers with seccomp disabled or weakened: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+func CheckSeccompBaseline(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that container seccomp profiles match the baseline profile"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	if t.Policy.Seccomp.Baseline == "" {
+		res.Skip("No baseline seccomp profile configured")
+		return
+	}
+	baseline, err := loadSeccompProfile(t.Policy.Seccomp.Baseline)
+	if err != nil {
+		res.Skip(fmt.Sprintf("Unable to load baseline seccomp profile: %v", err))
+		return
+	}
+	for _, c := range t.Containers {
+		if c.Info.ContainerJSONBase == nil || c.Info.HostConfig == nil {
+			continue
+		}
+		mode, profile, err := getSeccompProfile(c.Info)
+		var desc string
+		switch {
+		case err != nil:
+			desc = fmt.Sprintf("invalid profile (%v)", err)
+		case mode != "custom":
+			desc = mode + " profile"
+		default:
+			desc = profile.diff(baseline, effectiveCapabilities(c.Info))
+		}
+		if desc != "" {
+			findings = append(findings, fmt.Sprintf("%s: %s", c.ID, desc))
+		}
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Containers not using the baseline seccomp profile: " + strings.Join(findings, "; "))
+	}
+	return
+}
+
+// getSeccompProfile resolves the seccomp setting of a container to "default",
+// "unconfined" or "custom", returning the profile in the latter case. Seccomp
+// is not applied to privileged containers.
+func getSeccompProfile(info ContainerInfo) (mode string, profile *seccompProfile, err error) {
+	if info.HostConfig.Privileged {
+		return "unconfined", nil, nil
+	}
+	value, ok := getSecurityOpt(info.HostConfig.SecurityOpt, "seccomp")
+	switch value = strings.TrimSpace(value); {
+	case !ok || value == "builtin":
+		return "default", nil, nil
+	case value == "unconfined":
+		return "unconfined", nil, nil
+	}
+	profile = new(seccompProfile)
+	if err = json.Unmarshal([]byte(value), profile); err != nil {
+		return "custom", nil, err
+	}
+	return "custom", profile, nil
+}
+
+func loadSeccompProfile(path string) (*seccompProfile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profile := new(seccompProfile)
+	if err := json.Unmarshal(content, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// allows tells whether a process holding caps may always make a syscall.
+// Rules matching on syscall arguments only allow some calls, so they are not
+// taken into account.
+func (p *seccompProfile) allows(name string, caps []string) bool {
+	allowed := isAllowAction(p.DefaultAction)
+	for _, rule := range p.Syscalls {
+		if len(rule.Args) != 0 || !rule.appliesTo(caps) {
+			continue
+		}
+		if rule.Name == name || stringInSlice(name, rule.Names) {
+			if !isAllowAction(rule.Action) {
+				return false
+			}
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// appliesTo tells whether a rule is part of the filter of a process holding caps
+func (r seccompRule) appliesTo(caps []string) bool {
+	for _, capability := range normalizeCapabilities(r.Includes.Caps) {
+		if !stringInSlice(capability, caps) {
+			return false
+		}
+	}
+	for _, capability := range normalizeCapabilities(r.Excludes.Caps) {
+		if stringInSlice(capability, caps) {
+			return false
+		}
+	}
+	return true
+}
+
+// diff describes what a profile allows beyond the baseline, or returns "" if nothing
+func (p *seccompProfile) diff(baseline *seccompProfile, caps []string) string {
+	if isAllowAction(p.DefaultAction) && !isAllowAction(baseline.DefaultAction) {
+		return "default action " + p.DefaultAction
+	}
+	names := make(map[string]bool)
+	for _, profile := range []*seccompProfile{p, baseline} {
+		for _, rule := range profile.Syscalls {
+			names[rule.Name] = true
+			for _, name := range rule.Names {
+				names[name] = true
+			}
+		}
+	}
+	var extra []string
+	for name := range names {
+		if name != "" && p.allows(name, caps) && !baseline.allows(name, caps) {
+			extra = append(extra, name)
+		}
+	}
+	if len(extra) == 0 {
+		return ""
+	}
+	sort.Strings(extra)
+	return "also allows " + summarize(extra, 10)
+}
+
+func isAllowAction(action string) bool {
+	return action == "SCMP_ACT_ALLOW" || action == "SCMP_ACT_LOG"
+}
@@ -0,0 +1,75 @@
+package actuary
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSeccompProfile = `{
+	"defaultAction": "SCMP_ACT_ERRNO",
+	"syscalls": [
+		{"names": ["read", "write", "exit"], "action": "SCMP_ACT_ALLOW"},
+		{"names": ["mount", "umount2"], "action": "SCMP_ACT_ALLOW", "includes": {"caps": ["CAP_SYS_ADMIN"]}},
+		{"names": ["personality"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 0, "op": "SCMP_CMP_EQ"}]}
+	]
+}`
+
+func TestCheckSeccompProfileCustom(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{SecurityOpt: []string{"seccomp=" + testSeccompProfile}}}})
+	res := CheckSeccompProfile(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Profile only allows mount with CAP_SYS_ADMIN, should have passed.")
+
+	testTarget.Containers[0].Info.HostConfig.CapAdd = []string{"SYS_ADMIN"}
+	res = CheckSeccompProfile(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container holds CAP_SYS_ADMIN, mount is allowed.")
+	assert.Contains(t, res.Output, "allows mount, umount2")
+}
+
+func TestCheckSeccompProfileDefaultAllow(t *testing.T) {
+	profile := `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["keyctl"], "action": "SCMP_ACT_ERRNO"}]}`
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{SecurityOpt: []string{"seccomp=" + profile}}}})
+	res := CheckSeccompProfile(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Profile allows everything by default, should not have passed.")
+	assert.Contains(t, res.Output, "default action SCMP_ACT_ALLOW")
+}
+
+func TestCheckSeccompProfilePrivileged(t *testing.T) {
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{Privileged: true}}})
+	res := CheckSeccompProfile(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Seccomp does not apply to privileged containers, should not have passed.")
+}
+
+func TestCheckSeccompBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seccomp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	baseline := filepath.Join(dir, "baseline.json")
+	if err := ioutil.WriteFile(baseline, []byte(testSeccompProfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testTarget := newContainerTestTarget(t, types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &container.HostConfig{SecurityOpt: []string{"seccomp=" + testSeccompProfile}}}})
+	res := CheckSeccompBaseline(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "No baseline configured, should have been skipped.")
+
+	testTarget.Policy.Seccomp.Baseline = baseline
+	res = CheckSeccompBaseline(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Container uses the baseline profile, should have passed.")
+
+	profile := `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"names": ["read", "write", "exit", "ptrace"], "action": "SCMP_ACT_ALLOW"}]}`
+	testTarget.Containers[0].Info.HostConfig.SecurityOpt = []string{"seccomp=" + profile}
+	res = CheckSeccompBaseline(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Profile allows ptrace, should not have passed.")
+	assert.Contains(t, res.Output, "also allows ptrace")
+
+	testTarget.Containers[0].Info.HostConfig.SecurityOpt = nil
+	res = CheckSeccompBaseline(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Container uses the default profile, should not have passed.")
+}
@@ -11,23 +11,16 @@ import (
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"golang.org/x/net/context"
-	"log"
 )
 
 func CheckImageSprawl(t Target) (res Result) {
-	var allImageIDs []string
-	var runImageIDs []string
 	res.Name = "6.4 Avoid image sprawl"
 	imgOpts := types.ImageListOptions{All: false}
 	allImages, err := t.Client.ImageList(context.TODO(), imgOpts)
-
 	if err != nil {
 		res.Skip("Unable to retrieve image list")
 		return
 	}
-	for _, image := range allImages {
-		allImageIDs = append(allImageIDs, image.ID)
-	}
 
 	conOpts := types.ContainerListOptions{All: true}
 	containers, err := t.Client.ContainerList(context.TODO(), conOpts)
@@ -36,15 +29,25 @@ func CheckImageSprawl(t Target) (res Result) {
 		return
 	}
 
+	// Several containers may run the same image
+	usedImages := make(map[string]bool)
 	for _, container := range containers {
-		runImageIDs = append(runImageIDs, container.ImageID)
+		usedImages[container.ImageID] = true
+	}
+	inUse := 0
+	for _, image := range allImages {
+		if usedImages[image.ID] {
+			inUse++
+		}
 	}
-	if len(allImageIDs) > 100 {
-		output := fmt.Sprintf(`There are currently %d images`, len(allImageIDs))
+	max := t.Policy.Hygiene.maxImages()
+	if len(allImages) > max {
+		output := fmt.Sprintf("There are currently %d images, more than %d",
+			len(allImages), max)
 		res.Fail(output)
-	} else if len(runImageIDs) < (len(allImageIDs) / 2) {
-		output := fmt.Sprintf(`Only %d out of %d images are in use.`,
-			len(allImageIDs), len(runImageIDs))
+	} else if inUse < (len(allImages) / 2) {
+		output := fmt.Sprintf("Only %d out of %d images are in use",
+			inUse, len(allImages))
 		res.Fail(output)
 	} else {
 		res.Pass()
@@ -53,21 +56,23 @@ func CheckImageSprawl(t Target) (res Result) {
 }
 
 func CheckContainerSprawl(t Target) (res Result) {
-	var diff int
 	res.Name = "6.5 Avoid container sprawl"
-	options := types.ContainerListOptions{All: false}
-	runContainers, err := t.Client.ContainerList(context.TODO(), options)
-	options = types.ContainerListOptions{All: true}
+	options := types.ContainerListOptions{All: true}
 	allContainers, err := t.Client.ContainerList(context.TODO(), options)
 	if err != nil {
-		log.Printf("Unable to get container list")
-		return res
+		res.Skip("Unable to retrieve container list")
+		return
+	}
+	running := 0
+	for _, container := range allContainers {
+		if container.State == "running" {
+			running++
+		}
 	}
-	diff = len(allContainers) - len(runContainers)
-	if diff > 25 {
-		output := fmt.Sprintf(`There are currently a total of %d containers,
-			with only %d of them currently running`, len(allContainers),
-			len(runContainers))
+	max := t.Policy.Hygiene.maxStoppedContainers()
+	if len(allContainers)-running > max {
+		output := fmt.Sprintf("There are currently a total of %d containers, with only %d of them currently running",
+			len(allContainers), running)
 		res.Fail(output)
 	} else {
 		res.Pass()
@@ -77,19 +77,29 @@ func TestCheckContainerSprawlSuccess(t *testing.T) {
 
 func TestCheckContainerSprawlFail(t *testing.T) {
 	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
 	var containerList1 typeContainerList
-	var containerList2 typeContainerList
-	list1 := containerList1.populateContainerList(10).typeContainers
-	list2 := containerList2.populateContainerList(50).typeContainers
+	list1 := containerList1.populateContainerList(50).typeContainers
+	for i := range list1 {
+		list1[i].State = "exited"
+		if i < 10 {
+			list1[i].State = "running"
+		}
+	}
 	containerJSON1, err := json.Marshal(list1)
-	containerJSON2, err := json.Marshal(list2)
 	if err != nil {
 		t.Errorf("Could not convert process list to json.")
 	}
 	p1 := callPairing{"/containers/json", containerJSON1}
-	p2 := callPairing{"/container", containerJSON2}
-	ts := testTarget.testServer(t, p1, p2)
-	res := CheckContainerSprawl(*testTarget)
+	ts := testTarget.testServer(t, p1)
 	defer ts.Close()
+	res := CheckContainerSprawl(*testTarget)
 	assert.Equal(t, "WARN", res.Status, "More than 25 containers not running, should not pass.")
+	assert.Equal(t, "There are currently a total of 50 containers, with only 10 of them currently running", res.Output)
+
+	testTarget.Policy.Hygiene.MaxStoppedContainers = 40
+	res = CheckContainerSprawl(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Stopped containers within the policy, should pass.")
 }
@@ -0,0 +1,317 @@
+/*
+Package checks - 7 Docker Swarm Configuration
+This section lists the recommendations that alter and secure the behavior of Docker
+swarm mode: how managers are protected and kept in quorum, how node certificates are
+issued and rotated, and how traffic between nodes is encrypted.
+*/
+package actuary
+
+import (
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// swarmManagerCheck skips checks that need an active swarm manager, returning
+// false when the check cannot run on this node
+func swarmManagerCheck(t Target, res *Result) bool {
+	if t.Info.Swarm.LocalNodeState != swarm.LocalNodeStateActive {
+		res.Skip("Swarm mode is not active")
+		return false
+	}
+	if !t.Info.Swarm.ControlAvailable {
+		res.Skip("Node is not a swarm manager")
+		return false
+	}
+	return true
+}
+
+func CheckSwarmManagers(t Target) (res Result) {
+	var unreachable []string
+	res.Name = "7.2 Create the minimum number of manager nodes in a swarm"
+	if !swarmManagerCheck(t, &res) {
+		return
+	}
+	opts := types.NodeListOptions{Filters: filters.NewArgs()}
+	opts.Filters.Add("role", "manager")
+	nodes, err := t.Client.NodeList(context.TODO(), opts)
+	if err != nil {
+		res.Skip("Unable to retrieve node list")
+		return
+	}
+	managers := 0
+	for _, node := range nodes {
+		if node.ManagerStatus == nil {
+			continue
+		}
+		managers++
+		if node.ManagerStatus.Reachability != swarm.ReachabilityReachable {
+			unreachable = append(unreachable, node.Description.Hostname)
+		}
+	}
+	reachable := managers - len(unreachable)
+	switch {
+	case reachable <= managers/2:
+		output := fmt.Sprintf("Swarm has lost quorum: %d of %d managers unreachable: %s",
+			len(unreachable), managers, unreachable)
+		res.Fail(output)
+	case managers%2 == 0:
+		output := fmt.Sprintf("Swarm has an even number of managers (%d), which adds no fault tolerance",
+			managers)
+		res.Fail(output)
+	case managers > 7:
+		output := fmt.Sprintf("Swarm has %d managers, more than the recommended maximum of 7",
+			managers)
+		res.Fail(output)
+	case len(unreachable) != 0:
+		output := fmt.Sprintf("Unreachable managers: %s", unreachable)
+		res.Fail(output)
+	case managers < 3:
+		output := fmt.Sprintf("Swarm has %d manager, it cannot tolerate a manager failure",
+			managers)
+		res.Info(output)
+	default:
+		res.Pass()
+	}
+	return
+}
+
+func CheckSwarmBindInterface(t Target) (res Result) {
+	var listeners []string
+	res.Name = "7.3 Bind swarm services to a specific host interface"
+	if !swarmManagerCheck(t, &res) {
+		return
+	}
+	for _, ip := range getTCPListeners(t, 2377) {
+		if ip.IsUnspecified() {
+			listeners = append(listeners, ip.String())
+		}
+	}
+	if len(listeners) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Swarm management port 2377 listening on all interfaces: %s",
+			listeners)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckSwarmOverlayEncryption(t Target) (res Result) {
+	var badNetworks []string
+	res.Name = "7.4 Encrypt containers data exchange on different overlay network nodes"
+	if t.Info.Swarm.LocalNodeState != swarm.LocalNodeStateActive {
+		res.Skip("Swarm mode is not active")
+		return
+	}
+	networks, err := getNetworks(t)
+	if err != nil {
+		res.Skip("Cannot retrieve network list")
+		return
+	}
+	for _, network := range networks {
+		if network.Driver != "overlay" || network.Ingress {
+			continue
+		}
+		if _, ok := network.Options["encrypted"]; !ok {
+			badNetworks = append(badNetworks, describeNetwork(network))
+		}
+	}
+	if len(badNetworks) == 0 {
+		res.Pass()
+	} else {
+		res.Fail("Overlay networks without encryption: " + strings.Join(badNetworks, "; "))
+	}
+	return
+}
+
+func CheckSwarmAutolock(t Target) (res Result) {
+	res.Name = "7.6 Manage swarm with auto-lock mode"
+	if !swarmManagerCheck(t, &res) {
+		return
+	}
+	sw, err := t.Client.SwarmInspect(context.TODO())
+	if err != nil {
+		res.Skip("Unable to inspect swarm")
+		return
+	}
+	if sw.Spec.EncryptionConfig.AutoLockManagers {
+		res.Pass()
+	} else {
+		res.Fail("Swarm managers are not auto-locked")
+	}
+	return
+}
+
+func CheckSwarmNodeCertExpiry(t Target) (res Result) {
+	res.Name = "7.9 Rotate swarm node certificates as appropriate"
+	if !swarmManagerCheck(t, &res) {
+		return
+	}
+	sw, err := t.Client.SwarmInspect(context.TODO())
+	if err != nil {
+		res.Skip("Unable to inspect swarm")
+		return
+	}
+	maxDays := t.Policy.Swarm.maxNodeCertExpiryDays()
+	expiry := sw.Spec.CAConfig.NodeCertExpiry
+	// An unset expiry means swarmkit's default of 90 days
+	if expiry == 0 {
+		expiry = 90 * 24 * time.Hour
+	}
+	if expiry > time.Duration(maxDays)*24*time.Hour {
+		output := fmt.Sprintf("Node certificates are valid for %v, longer than %d days",
+			expiry, maxDays)
+		res.Fail(output)
+		return
+	}
+	res.Pass()
+	return
+}
+
+func CheckSwarmCARotation(t Target) (res Result) {
+	res.Name = "7.10 Rotate swarm root CA certificates as appropriate"
+	if !swarmManagerCheck(t, &res) {
+		return
+	}
+	sw, err := t.Client.SwarmInspect(context.TODO())
+	if err != nil {
+		res.Skip("Unable to inspect swarm")
+		return
+	}
+	if sw.RootRotationInProgress {
+		res.Info("Root CA rotation in progress")
+		return
+	}
+	block, _ := pem.Decode([]byte(sw.TLSInfo.TrustRoot))
+	if block == nil {
+		res.Skip("Unable to read swarm root CA certificate")
+		return
+	}
+	root, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		res.Skip("Unable to read swarm root CA certificate")
+		return
+	}
+	maxDays := t.Policy.Swarm.maxCAAgeDays()
+	age := time.Since(root.NotBefore)
+	if age > time.Duration(maxDays)*24*time.Hour {
+		output := fmt.Sprintf("Swarm root CA issued on %s has not been rotated in %d days",
+			root.NotBefore.Format("2006-01-02"), int(age.Hours()/24))
+		res.Fail(output)
+		return
+	}
+	res.Pass()
+	return
+}
+
+func CheckSwarmStaleNodes(t Target) (res Result) {
+	var staleNodes []string
+	res.Name = "Remove stale unreachable nodes from the swarm"
+	if !swarmManagerCheck(t, &res) {
+		return
+	}
+	nodes, err := t.Client.NodeList(context.TODO(), types.NodeListOptions{})
+	if err != nil {
+		res.Skip("Unable to retrieve node list")
+		return
+	}
+	threshold := time.Duration(t.Policy.Swarm.staleNodeHours()) * time.Hour
+	for _, node := range nodes {
+		if node.Status.State == swarm.NodeStateReady {
+			continue
+		}
+		if time.Since(node.UpdatedAt) > threshold {
+			staleNodes = append(staleNodes, fmt.Sprintf("%s (%s since %s)",
+				node.Description.Hostname, node.Status.State,
+				node.UpdatedAt.Format("2006-01-02 15:04")))
+		}
+	}
+	if len(staleNodes) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Nodes unreachable for more than %v: %s", threshold, staleNodes)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckSwarmWorkerIsolation(t Target) (res Result) {
+	res.Name = "Verify that swarm management is not reachable from worker nodes"
+	if t.Info.Swarm.LocalNodeState != swarm.LocalNodeStateActive {
+		res.Skip("Swarm mode is not active")
+		return
+	}
+	if t.Info.Swarm.ControlAvailable {
+		res.Skip("Node is a swarm manager")
+		return
+	}
+	if _, err := t.Client.SwarmInspect(context.TODO()); err == nil {
+		res.Fail("Swarm management API answers on a worker node")
+		return
+	}
+	if len(getTCPListeners(t, 2377)) != 0 {
+		res.Fail("Worker node listens on the swarm management port 2377")
+		return
+	}
+	res.Pass()
+	return
+}
+
+// getTCPListeners returns the addresses listening on a TCP port, read from
+// /proc/net/tcp and /proc/net/tcp6. Hosts with IPv6 disabled have no tcp6 file.
+func getTCPListeners(t Target, port int) (ips []net.IP) {
+	for _, name := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		content, err := ioutil.ReadFile(filepath.Join(t.BaseDir, name))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(content), "\n")
+		// Lines read "sl local_address rem_address st ...", with addresses
+		// as hex "ip:port" and state 0A for LISTEN
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[3] != "0A" {
+				continue
+			}
+			addr := strings.SplitN(fields[1], ":", 2)
+			if len(addr) != 2 {
+				continue
+			}
+			if p, err := strconv.ParseUint(addr[1], 16, 16); err != nil || int(p) != port {
+				continue
+			}
+			if ip := decodeProcNetIP(addr[0]); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return
+}
+
+// decodeProcNetIP decodes an address of /proc/net/tcp*, written as 32-bit
+// words in host byte order, assumed little-endian
+func decodeProcNetIP(s string) net.IP {
+	raw, err := hex.DecodeString(s)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		word := binary.LittleEndian.Uint32(raw[i : i+4])
+		binary.BigEndian.PutUint32(ip[i:i+4], word)
+	}
+	return ip
+}
@@ -0,0 +1,212 @@
+package actuary
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 7. Docker Swarm Configuration
+func newSwarmTestTarget(t *testing.T) *Target {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	testTarget.Info.Swarm.LocalNodeState = swarm.LocalNodeStateActive
+	testTarget.Info.Swarm.ControlAvailable = true
+	return testTarget
+}
+
+func managerNode(hostname string, reachability swarm.Reachability) swarm.Node {
+	return swarm.Node{
+		Description:   swarm.NodeDescription{Hostname: hostname},
+		Status:        swarm.NodeStatus{State: swarm.NodeStateReady},
+		ManagerStatus: &swarm.ManagerStatus{Reachability: reachability},
+	}
+}
+
+func TestCheckSwarmManagersSuccess(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	nodes := []swarm.Node{
+		managerNode("m1", swarm.ReachabilityReachable),
+		managerNode("m2", swarm.ReachabilityReachable),
+		managerNode("m3", swarm.ReachabilityReachable),
+	}
+	nJSON, err := json.Marshal(nodes)
+	if err != nil {
+		t.Errorf("Could not convert nodes to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/nodes", nJSON})
+	defer ts.Close()
+	res := CheckSwarmManagers(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Three reachable managers, should pass.")
+}
+
+func TestCheckSwarmManagersFail(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	nodes := []swarm.Node{
+		managerNode("m1", swarm.ReachabilityReachable),
+		managerNode("m2", swarm.ReachabilityUnreachable),
+		managerNode("m3", swarm.ReachabilityUnreachable),
+	}
+	nJSON, err := json.Marshal(nodes)
+	if err != nil {
+		t.Errorf("Could not convert nodes to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/nodes", nJSON})
+	defer ts.Close()
+	res := CheckSwarmManagers(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Two of three managers unreachable, should not pass.")
+}
+
+func TestCheckSwarmManagersSkip(t *testing.T) {
+	testTarget, err := NewTestTarget([]string{""})
+	if err != nil {
+		t.Errorf("Could not create testTarget")
+	}
+	res := CheckSwarmManagers(*testTarget)
+	assert.Equal(t, "SKIP", res.Status, "Swarm mode inactive, should skip.")
+}
+
+func TestCheckSwarmAutolockSuccess(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	var sw swarm.Swarm
+	sw.Spec.EncryptionConfig.AutoLockManagers = true
+	sJSON, err := json.Marshal(sw)
+	if err != nil {
+		t.Errorf("Could not convert swarm to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/swarm", sJSON})
+	defer ts.Close()
+	res := CheckSwarmAutolock(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Autolock enabled, should pass.")
+}
+
+func TestCheckSwarmAutolockFail(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	var sw swarm.Swarm
+	sJSON, err := json.Marshal(sw)
+	if err != nil {
+		t.Errorf("Could not convert swarm to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/swarm", sJSON})
+	defer ts.Close()
+	res := CheckSwarmAutolock(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Autolock disabled, should not pass.")
+}
+
+func TestCheckSwarmNodeCertExpiryFail(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	var sw swarm.Swarm
+	sw.Spec.CAConfig.NodeCertExpiry = 365 * 24 * time.Hour
+	sJSON, err := json.Marshal(sw)
+	if err != nil {
+		t.Errorf("Could not convert swarm to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/swarm", sJSON})
+	defer ts.Close()
+	res := CheckSwarmNodeCertExpiry(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Node certificates valid for a year, should not pass.")
+	testTarget.Policy.Swarm.MaxNodeCertExpiryDays = 365
+	res = CheckSwarmNodeCertExpiry(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Policy allows a year, should pass.")
+}
+
+func TestCheckSwarmOverlayEncryptionFail(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	var networks = []types.NetworkResource{
+		{Name: "ingress", Driver: "overlay", Ingress: true},
+		{Name: "secure", Driver: "overlay", Options: map[string]string{"encrypted": ""}},
+		{Name: "plain", Driver: "overlay"},
+	}
+	nJSON, err := json.Marshal(networks)
+	if err != nil {
+		t.Errorf("Could not convert networks to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/networks", nJSON})
+	defer ts.Close()
+	res := CheckSwarmOverlayEncryption(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Unencrypted overlay network, should not pass.")
+}
+
+func TestCheckSwarmStaleNodesFail(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	stale := swarm.Node{
+		Description: swarm.NodeDescription{Hostname: "w1"},
+		Status:      swarm.NodeStatus{State: swarm.NodeStateDown},
+	}
+	stale.UpdatedAt = time.Now().Add(-72 * time.Hour)
+	nJSON, err := json.Marshal([]swarm.Node{managerNode("m1", swarm.ReachabilityReachable), stale})
+	if err != nil {
+		t.Errorf("Could not convert nodes to json.")
+	}
+	ts := testTarget.testServer(t, callPairing{"/nodes", nJSON})
+	defer ts.Close()
+	res := CheckSwarmStaleNodes(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Node down for three days, should not pass.")
+}
+
+// Writes /proc/net/tcp under BaseDir with a listener on each address, e.g.
+// "00000000:0949" for 0.0.0.0:2377. No tcp6 file is written, as on hosts
+// with IPv6 disabled.
+func writeProcNetTCP(t *testing.T, target *Target, addrs ...string) {
+	testDataDir(t, target)
+	content := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	for i, addr := range addrs {
+		content += fmt.Sprintf("%4d: %s 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 %d 1\n",
+			i, addr, 1000+i)
+	}
+	dir := filepath.Join(target.BaseDir, "proc/net")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "tcp"), []byte(content), 0444); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckSwarmBindInterfaceSuccess(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	writeProcNetTCP(t, testTarget, "0100007F:0949", "00000000:0016")
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckSwarmBindInterface(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Management port bound to 127.0.0.1, should pass.")
+}
+
+func TestCheckSwarmBindInterfaceFail(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	writeProcNetTCP(t, testTarget, "00000000:0949")
+	defer os.RemoveAll(testTarget.BaseDir)
+	res := CheckSwarmBindInterface(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Management port bound to every interface, should not pass.")
+	assert.Equal(t, "Swarm management port 2377 listening on all interfaces: [0.0.0.0]", res.Output)
+}
+
+func TestCheckSwarmWorkerIsolation(t *testing.T) {
+	testTarget := newSwarmTestTarget(t)
+	testTarget.Info.Swarm.ControlAvailable = false
+	writeProcNetTCP(t, testTarget, "00000000:0016")
+	defer os.RemoveAll(testTarget.BaseDir)
+	ts := testTarget.testServer(t)
+	defer ts.Close()
+	res := CheckSwarmWorkerIsolation(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Worker without the management port, should pass.")
+
+	writeProcNetTCP(t, testTarget, "00000000:0949")
+	defer os.RemoveAll(testTarget.BaseDir)
+	res = CheckSwarmWorkerIsolation(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Worker listening on the management port, should not pass.")
+}
+
+func TestDecodeProcNetIP(t *testing.T) {
+	assert.Equal(t, "127.0.0.1", decodeProcNetIP("0100007F").String())
+	assert.Equal(t, "::1", decodeProcNetIP("00000000000000000000000001000000").String())
+	assert.Nil(t, decodeProcNetIP("zz"))
+}
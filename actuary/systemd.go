@@ -0,0 +1,305 @@
+/*
+Package checks - 3 Docker daemon configuration files (systemd units)
+The docker.service and docker.socket units decide how the daemon is started and who
+can reach its socket. Settings in the unit, its drop-in directories and the
+environment files it loads can undo the hardening applied elsewhere.
+*/
+package actuary
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unit directories in order of decreasing precedence
+var unitDirs = []string{"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// systemdUnit maps section names to the values assigned to each key
+type systemdUnit map[string]map[string][]string
+
+// Get returns the last value assigned to a key
+func (u systemdUnit) Get(section, key string) string {
+	values := u[section][key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+var unitVarRegexp = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// loadUnit parses a unit file and merges its drop-in directories on top of it
+func loadUnit(t Target, name string) (unit systemdUnit, err error) {
+	unit = make(systemdUnit)
+	found := false
+	for _, dir := range unitDirs {
+		fpath := filepath.Join(t.BaseDir, dir, name)
+		if _, err := os.Stat(fpath); err == nil {
+			if err = parseUnitFile(fpath, unit); err != nil {
+				return nil, err
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	// Drop-ins are applied in filename order; a file in a directory with higher
+	// precedence hides a file of the same name in a lower one
+	dropins := make(map[string]string)
+	for i := len(unitDirs) - 1; i >= 0; i-- {
+		matches, _ := filepath.Glob(filepath.Join(t.BaseDir, unitDirs[i], name+".d", "*.conf"))
+		for _, match := range matches {
+			dropins[filepath.Base(match)] = match
+		}
+	}
+	var names []string
+	for dropin := range dropins {
+		names = append(names, dropin)
+	}
+	sort.Strings(names)
+	for _, dropin := range names {
+		if err = parseUnitFile(dropins[dropin], unit); err != nil {
+			return nil, err
+		}
+	}
+	return unit, nil
+}
+
+// parseUnitFile adds the assignments found in a unit file to unit. An empty
+// assignment resets the list of values for a key, as systemd does.
+func parseUnitFile(path string, unit systemdUnit) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var section, line string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || line == "" {
+			line = ""
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			if unit[section] == nil {
+				unit[section] = make(map[string][]string)
+			}
+		} else if kv := strings.SplitN(line, "=", 2); len(kv) == 2 && section != "" {
+			key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+			if val == "" {
+				unit[section][key] = nil
+			} else {
+				unit[section][key] = append(unit[section][key], val)
+			}
+		}
+		line = ""
+	}
+	return scanner.Err()
+}
+
+// getServiceEnv returns the variables set through Environment and EnvironmentFile,
+// along with where each was defined
+func getServiceEnv(t Target, unit systemdUnit) (env map[string]string, source map[string]string) {
+	env = make(map[string]string)
+	source = make(map[string]string)
+	for _, value := range unit["Service"]["Environment"] {
+		for _, assignment := range splitCommandLine(value) {
+			if kv := strings.SplitN(assignment, "=", 2); len(kv) == 2 {
+				env[kv[0]] = kv[1]
+				source[kv[0]] = "Environment"
+			}
+		}
+	}
+	for _, value := range unit["Service"]["EnvironmentFile"] {
+		path := strings.TrimPrefix(value, "-")
+		file, err := os.Open(filepath.Join(t.BaseDir, path))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+				continue
+			}
+			if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+				key := strings.TrimSpace(strings.TrimPrefix(kv[0], "export "))
+				env[key] = strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+				source[key] = path
+			}
+		}
+		file.Close()
+	}
+	return
+}
+
+// getServiceCmdline returns the daemon's ExecStart arguments with environment
+// variables expanded. "$VAR" expands to one argument per word and "${VAR}" to
+// a single argument.
+func getServiceCmdline(t Target, unit systemdUnit) (args []string) {
+	execStart := unit.Get("Service", "ExecStart")
+	if execStart == "" {
+		return
+	}
+	execStart = strings.TrimLeft(execStart, "-@+!:")
+	env, _ := getServiceEnv(t, unit)
+	for _, arg := range splitCommandLine(execStart) {
+		if match := unitVarRegexp.FindStringSubmatch(arg); match != nil {
+			args = append(args, strings.Fields(env[match[1]])...)
+			continue
+		}
+		args = append(args, os.Expand(arg, func(key string) string {
+			return env[key]
+		}))
+	}
+	return
+}
+
+// splitCommandLine splits a command line into words, honouring quotes and escapes
+func splitCommandLine(s string) (words []string) {
+	var word []rune
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word = append(word, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word = append(word, r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, string(word))
+				word = word[:0]
+				inWord = false
+			}
+		default:
+			word = append(word, r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, string(word))
+	}
+	return
+}
+
+func CheckServiceTCPWithoutTLS(t Target) (res Result) {
+	var tcpHosts []string
+	res.Name = "Verify that docker.service does not expose the daemon over TCP without TLS"
+	unit, err := loadUnit(t, "docker.service")
+	if err != nil {
+		res.Skip("File could not be accessed")
+		return
+	}
+	args := getServiceCmdline(t, unit)
+	for _, host := range parseHostFlags(args) {
+		if strings.HasPrefix(host, "tcp://") {
+			tcpHosts = append(tcpHosts, host)
+		}
+	}
+	tlsVerify := stringInSlice("--tlsverify", args) || stringInSlice("--tlsverify=true", args)
+	if config, err := getDaemonJSON(t); err == nil {
+		if v, ok := config["tlsverify"].(bool); ok && v {
+			tlsVerify = true
+		}
+	}
+	if len(tcpHosts) == 0 || tlsVerify {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Daemon listens on TCP without --tlsverify: %s", tcpHosts)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckServiceProxyCredentials(t Target) (res Result) {
+	var leaks []string
+	res.Name = "Verify that docker.service does not store proxy credentials in its environment"
+	unit, err := loadUnit(t, "docker.service")
+	if err != nil {
+		res.Skip("File could not be accessed")
+		return
+	}
+	env, source := getServiceEnv(t, unit)
+	for key, val := range env {
+		if !strings.HasSuffix(strings.ToLower(key), "_proxy") {
+			continue
+		}
+		proxy, err := url.Parse(val)
+		if err != nil || proxy.User == nil {
+			continue
+		}
+		if _, hasPassword := proxy.User.Password(); hasPassword {
+			leaks = append(leaks, fmt.Sprintf("%s (%s)", key, source[key]))
+		}
+	}
+	sort.Strings(leaks)
+	if len(leaks) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("Proxy credentials found in: %s", leaks)
+		res.Fail(output)
+	}
+	return
+}
+
+func CheckSocketUnitMode(t Target) (res Result) {
+	var findings []string
+	res.Name = "Verify that docker.socket sets SocketMode to 0660 or more restrictive and SocketGroup to docker"
+	unit, err := loadUnit(t, "docker.socket")
+	if err != nil {
+		res.Skip("File could not be accessed")
+		return
+	}
+	// systemd creates sockets with mode 0666 and group root unless told otherwise
+	mode := unit.Get("Socket", "SocketMode")
+	if mode == "" {
+		mode = "0666"
+	}
+	perms, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perms&^0660 != 0 {
+		findings = append(findings, fmt.Sprintf("SocketMode=%s", mode))
+	}
+	group := unit.Get("Socket", "SocketGroup")
+	if group != "docker" {
+		findings = append(findings, fmt.Sprintf("SocketGroup=%s", group))
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		output := fmt.Sprintf("docker.socket has unsafe settings: %s", findings)
+		res.Fail(output)
+	}
+	return
+}
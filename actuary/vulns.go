@@ -0,0 +1,237 @@
+/*
+Package checks - 4 Container Images and Build File (known vulnerabilities)
+Installed packages are matched against advisories in the OSV format, read from
+local files so that no network access is needed while scanning.
+*/
+package actuary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// osvEntry holds the parts of an OSV advisory used for matching
+type osvEntry struct {
+	ID       string
+	Affected []osvAffected
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string
+		Name      string
+	}
+	Ranges []struct {
+		Type   string
+		Events []map[string]string
+	}
+}
+
+// osvDatabase indexes the affected entries of the advisories by ecosystem and
+// package name
+type osvDatabase map[string][]osvMatch
+
+type osvMatch struct {
+	ID string
+	// Release is the ecosystem suffix, e.g. "11" in "Debian:11"
+	Release  string
+	Affected osvAffected
+}
+
+// vulnerablePackage is an installed package affected by an advisory with a fix
+type vulnerablePackage struct {
+	Package imagePackage
+	ID      string
+	Fixed   string
+}
+
+var osvCache = struct {
+	sync.Mutex
+	dbs map[string]osvDatabase
+}{dbs: make(map[string]osvDatabase)}
+
+// OSV ecosystem names and version comparison of each supported distribution
+var distroEcosystems = map[string]struct {
+	Name    string
+	Compare func(a, b string) int
+}{
+	"debian": {"Debian", compareDpkgVersions},
+	"ubuntu": {"Ubuntu", compareDpkgVersions},
+	"alpine": {"Alpine", compareApkVersions},
+}
+
+func CheckImageVulnerabilities(t Target) (res Result) {
+	var findings []string
+	res.Name = "4.4 Scan and rebuild the images to include security patches"
+	if !t.Containers.Running() {
+		res.Skip("No running containers")
+		return
+	}
+	if t.Policy.Vulnerabilities.OSVPath == "" {
+		res.Skip("No vulnerability database configured")
+		return
+	}
+	db, err := loadOSVDatabase(t.Policy.Vulnerabilities.OSVPath)
+	if err != nil {
+		res.Skip(fmt.Sprintf("Unable to load vulnerability database: %v", err))
+		return
+	}
+	scanned := 0
+	for _, img := range getImagesInUse(t) {
+		fs, err := scanImage(t, img.ID)
+		if err != nil {
+			continue
+		}
+		distro := getImageDistro(fs)
+		pkgs := getImagePackages(fs)
+		if _, ok := distroEcosystems[distro.ID]; !ok || len(pkgs) == 0 {
+			continue
+		}
+		scanned++
+		var matches []string
+		for _, vuln := range db.match(distro, pkgs) {
+			matches = append(matches, fmt.Sprintf("%s %s (%s, fixed in %s)",
+				vuln.Package.Name, vuln.Package.Version, vuln.ID, vuln.Fixed))
+		}
+		if len(matches) != 0 {
+			findings = append(findings, fmt.Sprintf("%s used by %s: %s",
+				img.Ref, img.Containers, summarize(matches, 10)))
+		}
+	}
+	if scanned == 0 {
+		res.Skip("No images with a supported package database")
+		return
+	}
+	if len(findings) == 0 {
+		res.Pass()
+	} else {
+		res.Fail(strings.Join(findings, "; "))
+	}
+	return
+}
+
+// loadOSVDatabase reads every .json file below path, or path itself if it is
+// a file. Each file holds one advisory or a list of them.
+func loadOSVDatabase(path string) (osvDatabase, error) {
+	osvCache.Lock()
+	defer osvCache.Unlock()
+	if db, ok := osvCache.dbs[path]; ok {
+		return db, nil
+	}
+	db := make(osvDatabase)
+	err := filepath.Walk(path, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(fpath) != ".json" {
+			return nil
+		}
+		content, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return err
+		}
+		var entries []osvEntry
+		if err := json.Unmarshal(content, &entries); err != nil {
+			var entry osvEntry
+			if err := json.Unmarshal(content, &entry); err != nil {
+				return fmt.Errorf("%s: %v", fpath, err)
+			}
+			entries = []osvEntry{entry}
+		}
+		for _, entry := range entries {
+			db.add(entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	osvCache.dbs[path] = db
+	return db, nil
+}
+
+func (db osvDatabase) add(entry osvEntry) {
+	for _, affected := range entry.Affected {
+		parts := strings.SplitN(affected.Package.Ecosystem, ":", 2)
+		match := osvMatch{ID: entry.ID, Affected: affected}
+		if len(parts) == 2 {
+			match.Release = parts[1]
+		}
+		key := parts[0] + "/" + affected.Package.Name
+		db[key] = append(db[key], match)
+	}
+}
+
+// match returns the packages affected by an advisory that has a fix
+func (db osvDatabase) match(distro imageDistro, pkgs []imagePackage) (vulns []vulnerablePackage) {
+	eco := distroEcosystems[distro.ID]
+	release := distroRelease(distro)
+	for _, pkg := range pkgs {
+		for _, m := range db[eco.Name+"/"+pkg.Source] {
+			if m.Release != "" && !stringInSlice(release, strings.Split(m.Release, ":")) {
+				continue
+			}
+			if fixed := fixedVersion(m.Affected, pkg.Version, eco.Compare); fixed != "" {
+				vulns = append(vulns, vulnerablePackage{pkg, m.ID, fixed})
+			}
+		}
+	}
+	sort.Slice(vulns, func(i, j int) bool {
+		if vulns[i].Package.Name != vulns[j].Package.Name {
+			return vulns[i].Package.Name < vulns[j].Package.Name
+		}
+		return vulns[i].ID < vulns[j].ID
+	})
+	return
+}
+
+// distroRelease returns the release as OSV ecosystems name it: "11" for
+// Debian, "22.04" for Ubuntu and "v3.16" for Alpine
+func distroRelease(distro imageDistro) string {
+	parts := strings.Split(distro.Version, ".")
+	switch distro.ID {
+	case "debian":
+		return parts[0]
+	case "alpine":
+		if len(parts) > 1 {
+			return "v" + parts[0] + "." + parts[1]
+		}
+		return "v" + distro.Version
+	}
+	return distro.Version
+}
+
+// fixedVersion returns the version fixing the advisory if version falls in one
+// of its ranges, or "" if it is not affected or no fix is known
+func fixedVersion(affected osvAffected, version string, compare func(a, b string) int) string {
+	for _, r := range affected.Ranges {
+		if r.Type != "ECOSYSTEM" {
+			continue
+		}
+		vulnerable := false
+		fixed := ""
+		for _, event := range r.Events {
+			if v, ok := event["introduced"]; ok && (v == "0" || compare(version, v) >= 0) {
+				vulnerable = true
+			}
+			if v, ok := event["fixed"]; ok {
+				if compare(version, v) >= 0 {
+					vulnerable = false
+				} else if vulnerable {
+					fixed = v
+					break
+				}
+			}
+		}
+		if vulnerable && fixed != "" {
+			return fixed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package actuary
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testAdvisories = `[
+  {
+    "id": "DSA-5103-1",
+    "affected": [{
+      "package": {"ecosystem": "Debian:11", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1n-0+deb11u1"}]}]
+    }]
+  },
+  {
+    "id": "DSA-0000-1",
+    "affected": [{
+      "package": {"ecosystem": "Debian:10", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1z"}]}]
+    }]
+  }
+]`
+
+func vulnTestTarget(t *testing.T, id string, status string) (*Target, func()) {
+	archive := buildImageArchive(t, buildTar(t, []tarEntry{
+		{"usr/lib/os-release", 0644, "ID=debian\nVERSION_ID=\"11\"\n"},
+		{"var/lib/dpkg/status", 0644, status},
+	}))
+	dir, err := ioutil.TempDir("", "osv")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %v", err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "debian.json"), []byte(testAdvisories), 0644)
+	testTarget := imageFSTestTarget(t, id)
+	testTarget.Policy.Vulnerabilities.OSVPath = dir
+	ts := testTarget.testServer(t, callPairing{"/images/get", archive})
+	return testTarget, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCheckImageVulnerabilitiesSuccess(t *testing.T) {
+	testTarget, cleanup := vulnTestTarget(t, "sha256:patched",
+		"Package: libssl1.1\nSource: openssl\nVersion: 1.1.1n-0+deb11u3\n")
+	defer cleanup()
+	res := CheckImageVulnerabilities(*testTarget)
+	assert.Equal(t, "PASS", res.Status, "Packages are patched, should have passed.")
+}
+
+func TestCheckImageVulnerabilitiesFail(t *testing.T) {
+	testTarget, cleanup := vulnTestTarget(t, "sha256:unpatched",
+		"Package: libssl1.1\nSource: openssl\nVersion: 1.1.1k-1+deb11u1\n")
+	defer cleanup()
+	res := CheckImageVulnerabilities(*testTarget)
+	assert.Equal(t, "WARN", res.Status, "Package has a known fix, should not have passed.")
+	assert.Equal(t, "app:latest used by [Container_id1]: libssl1.1 1.1.1k-1+deb11u1 (DSA-5103-1, fixed in 1.1.1n-0+deb11u1)",
+		res.Output)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"github.com/diogomonica/actuary/cmd/actuary/check"
+	"github.com/diogomonica/actuary/cmd/actuary/lint"
+	"github.com/diogomonica/actuary/cmd/actuary/sbom"
 	"github.com/diogomonica/actuary/cmd/actuary/server"
 	"github.com/spf13/cobra"
 	"os"
@@ -20,6 +22,8 @@ func init() {
 	mainCmd.AddCommand(
 		server.ServerCmd,
 		check.CheckCmd,
+		lint.LintCmd,
+		sbom.SbomCmd,
 	)
 }
 
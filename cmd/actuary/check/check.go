@@ -119,7 +119,11 @@ var (
 			} else {
 				log.Fatalf("Unsupported number of arguments. Use -h for help")
 			}
+			trgt.Policy = tomlProfile.Policy
 			actions := actuary.GetAuditDefinitions()
+			for _, spec := range tomlProfile.Files {
+				actions[spec.ID] = spec.Audit
+			}
 			for category := range tomlProfile.Audit {
 				checks := tomlProfile.Audit[category].Checklist
 				for _, check := range checks {
@@ -0,0 +1,69 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/diogomonica/actuary/actuary"
+	"github.com/diogomonica/actuary/oututils"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var output string
+var buildArgs []string
+
+func init() {
+	dockerfileCmd.Flags().StringVarP(&output, "output", "o", "", "output filename")
+	dockerfileCmd.Flags().StringArrayVar(&buildArgs, "build-arg", nil, "Build argument used to expand ARG instructions, as <name>=<value>")
+	LintCmd.AddCommand(dockerfileCmd)
+}
+
+var (
+	LintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Check build files against actuary's image rules",
+	}
+
+	dockerfileCmd = &cobra.Command{
+		Use:   "dockerfile <path...>",
+		Short: "Check Dockerfiles before the images are built",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("at least one Dockerfile is required")
+			}
+			argValues := make(map[string]string)
+			for _, arg := range buildArgs {
+				kv := strings.SplitN(arg, "=", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("invalid build argument: %s", arg)
+				}
+				argValues[kv[0]] = kv[1]
+			}
+			results, err := actuary.LintDockerfiles(args, argValues)
+			if err != nil {
+				return err
+			}
+			rep := oututils.CreateReport(output)
+			rep.Results = results
+			switch strings.ToLower(output) {
+			case "json":
+				rep.WriteJSON()
+			case "xml":
+				rep.WriteXML()
+			default:
+				for _, res := range rep.Results {
+					oututils.ConsolePrint(res)
+				}
+			}
+			failed := 0
+			for _, res := range results {
+				if res.Status == "WARN" {
+					failed++
+				}
+			}
+			if failed != 0 {
+				return fmt.Errorf("%d of %d rules failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+)
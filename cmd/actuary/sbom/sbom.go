@@ -0,0 +1,76 @@
+package sbom
+
+import (
+	"fmt"
+	"github.com/diogomonica/actuary/actuary"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var format string
+var outputDir string
+var tlsPath string
+var dockerServer string
+
+func init() {
+	SbomCmd.Flags().StringVar(&format, "format", "spdx", "SBOM format: spdx or cyclonedx")
+	SbomCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Directory the SBOM files are written to")
+	SbomCmd.Flags().StringVarP(&tlsPath, "tlsPath", "t", "", "Path to load certificates from")
+	SbomCmd.Flags().StringVarP(&dockerServer, "dockerServer", "d", "", "Docker server to connect to tcp://<docker host>:<port>")
+}
+
+// Turns an image reference into a file name
+func fileName(sbom actuary.ImageSBOM) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, sbom.Ref)
+	id := strings.TrimPrefix(sbom.ImageID, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	ext := ".spdx.json"
+	if strings.ToLower(format) == "cyclonedx" {
+		ext = ".cdx.json"
+	}
+	return fmt.Sprintf("%s-%s%s", name, id, ext)
+}
+
+var (
+	SbomCmd = &cobra.Command{
+		Use:   "sbom",
+		Short: "Write a software bill of materials for each image used by a container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if tlsPath != "" {
+				os.Setenv("DOCKER_CERT_PATH", tlsPath)
+			}
+			if dockerServer != "" {
+				os.Setenv("DOCKER_HOST", dockerServer)
+			} else {
+				os.Setenv("DOCKER_HOST", "unix:///var/run/docker.sock")
+			}
+			trgt, err := actuary.NewTarget()
+			if err != nil {
+				log.Fatalf("Unable to connect to Docker daemon: %s", err)
+			}
+			sboms, err := actuary.GenerateSBOMs(trgt, format)
+			if err != nil {
+				return err
+			}
+			for _, sbom := range sboms {
+				path := filepath.Join(outputDir, fileName(sbom))
+				if err := ioutil.WriteFile(path, sbom.Document, 0644); err != nil {
+					return err
+				}
+				fmt.Println(path)
+			}
+			return nil
+		},
+	}
+)
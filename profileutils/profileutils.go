@@ -4,6 +4,7 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"github.com/diogomonica/actuary/actuary"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -16,9 +17,11 @@ type Profile struct {
 		Name      string
 		Checklist []string
 	}
+	Files []actuary.FileSpec
+	actuary.Policy
 }
 
-//GetFromURL reads audit profile using the API
+// GetFromURL reads audit profile using the API
 func GetFromURL(hash string) (p Profile, err error) {
 	var url string
 	url = serverAddr + hash
@@ -37,7 +40,7 @@ func GetFromURL(hash string) (p Profile, err error) {
 	return p, err
 }
 
-//GetFromFile reads an audit profile from a filesystem path
+// GetFromFile reads an audit profile from a filesystem path
 func GetFromFile(path string) (p Profile) {
 	_, err := toml.DecodeFile(path, &p)
 	if err != nil {
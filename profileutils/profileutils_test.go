@@ -48,3 +48,23 @@ Checklist = [
 	}
 	dummy.Destroy()
 }
+
+func TestGetFromFileFileSpecs(t *testing.T) {
+	dummy, _ := CreateProfile("/tmp/testprofile_files.toml")
+	data := `[[Files]]
+
+ID = "sshd_config_perms"
+Name = "Verify that sshd_config permissions are set to 600"
+Path = "/etc/ssh/sshd_config"
+Owner = "root"
+Perms = "0600"`
+	dummy.Update(data)
+	profile := GetFromFile(dummy.path)
+	if len(profile.Files) != 1 {
+		t.Fatalf("Expected 1 file spec, got %d instead", len(profile.Files))
+	}
+	if profile.Files[0].ID != "sshd_config_perms" || profile.Files[0].Perms != "0600" {
+		t.Errorf("Unexpected file spec: %+v", profile.Files[0])
+	}
+	dummy.Destroy()
+}